@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"sync"
@@ -82,7 +83,7 @@ func cpWorker(jobs chan bts.TeamList, results chan<- bts.TeamList, oc *outcomes,
 	defer wg.Done()
 	for j := range jobs {
 		c := make(chan bts.TeamList, 100)
-		go bts.TeamPermute(j, c)
+		go bts.TeamPermute(context.Background(), j, c)
 		for tl := range c {
 			if oc.validate(tl) {
 				results <- tl
@@ -3,7 +3,11 @@ package main
 import (
 	"fmt"
 	"io"
+	"math"
 	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
 )
 
 func TestHandler(t *testing.T) {
@@ -21,3 +25,120 @@ func TestHandler(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestRequestMessageValidate(t *testing.T) {
+	zero := 0
+	two := 2
+	tests := []struct {
+		name    string
+		rm      RequestMessage
+		wantErr bool
+	}{
+		{"plain", RequestMessage{Picker: "Phil K"}, false},
+		{"shard", RequestMessage{Picker: "Phil K", Exhaustive: true, Shard: &zero, Shards: 2, Job: "j"}, false},
+		{"shard out of range", RequestMessage{Picker: "Phil K", Shard: &two, Shards: 2, Job: "j"}, true},
+		{"shard without job", RequestMessage{Picker: "Phil K", Shard: &zero, Shards: 2}, true},
+		{"reduce", RequestMessage{Picker: "Phil K", Reduce: true, Job: "j"}, false},
+		{"reduce without job", RequestMessage{Picker: "Phil K", Reduce: true}, true},
+		{"reduce and shard", RequestMessage{Picker: "Phil K", Reduce: true, Shard: &zero, Shards: 2, Job: "j"}, true},
+		{"objective", RequestMessage{Picker: "Phil K", Objective: "weeks"}, false},
+		{"unknown objective", RequestMessage{Picker: "Phil K", Objective: "bogus"}, true},
+		{"outlast", RequestMessage{Picker: "Phil K", Objective: "outlast", Opponents: []string{"Luke M"}}, false},
+		{"pool", RequestMessage{Picker: "Phil K", Pool: true}, false},
+		{"pool and shard", RequestMessage{Picker: "Phil K", Pool: true, Shard: &zero, Shards: 2, Job: "j"}, true},
+		{"opponents without outlast", RequestMessage{Picker: "Phil K", Opponents: []string{"Luke M"}}, true},
+		{"rules", RequestMessage{Picker: "Phil K", Rules: "cancelled=win,no-contest=loss"}, false},
+		{"unknown rule", RequestMessage{Picker: "Phil K", Rules: "cancelled=tie"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rm.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDistinctStreakPredictions(t *testing.T) {
+	fs := &firestore.Client{}
+	a := fs.Doc("teams/a")
+	b := fs.Doc("teams/b")
+	sps := []StreakPrediction{
+		{CumulativeProbability: .5, Weeks: []Week{{WeekNumber: 1, Pick: []*firestore.DocumentRef{a}}, {WeekNumber: 2, Pick: []*firestore.DocumentRef{b}}}},
+		{CumulativeProbability: .4, Weeks: []Week{{WeekNumber: 1, Pick: []*firestore.DocumentRef{b}}, {WeekNumber: 2, Pick: []*firestore.DocumentRef{a}}}},
+		{CumulativeProbability: .5, Weeks: []Week{{WeekNumber: 1, Pick: []*firestore.DocumentRef{a}}, {WeekNumber: 2, Pick: []*firestore.DocumentRef{b}}}},
+	}
+	out := distinctStreakPredictions(sps)
+	if len(out) != 2 {
+		t.Fatalf("expected 2 distinct predictions, got %d", len(out))
+	}
+	if out[1].CumulativeProbability != .4 {
+		t.Errorf("expected second prediction to have probability %f, got %f", .4, out[1].CumulativeProbability)
+	}
+}
+
+func TestMakePickerPrediction(t *testing.T) {
+	fs := &firestore.Client{}
+	a := fs.Doc("teams/a")
+	b := fs.Doc("teams/b")
+	c := fs.Doc("teams/c")
+	// Under an objective other than survival, the top-ranked streak need not be the most likely to survive the season.
+	sps := []StreakPrediction{
+		{CumulativeProbability: .3, Score: 1, Weeks: []Week{{WeekNumber: 1, Pick: []*firestore.DocumentRef{a}}}},
+		{CumulativeProbability: .5, Score: 3, Weeks: []Week{{WeekNumber: 1, Pick: []*firestore.DocumentRef{b}}}},
+		{CumulativeProbability: .6, Score: 2, Weeks: []Week{{WeekNumber: 1, Pick: []*firestore.DocumentRef{c}}}},
+	}
+	pp := makePickerPrediction(sps, 1, time.Now())
+	if pp.BestPick[0] != b || pp.Probability != .5 {
+		t.Errorf("expected best pick %s with probability %f, got %s with probability %f", b.ID, .5, pp.BestPick[0].ID, pp.Probability)
+	}
+	costs := []float64{0, -.1, .2}
+	for i, cost := range costs {
+		if math.Abs(pp.PossiblePicks[i].ProbabilityCost-cost) > 1e-9 {
+			t.Errorf("option %d: expected probability cost %f, got %f", i, cost, pp.PossiblePicks[i].ProbabilityCost)
+		}
+	}
+}
+
+func TestSurvivalCurve(t *testing.T) {
+	sp := StreakPrediction{Weeks: []Week{
+		{WeekNumber: 3, Probabilities: []float64{.5}},
+		{WeekNumber: 4},
+		{WeekNumber: 5, Probabilities: []float64{.5, .8}},
+	}}
+	want := []float64{.5, .5, .2}
+	got := survivalCurve(sp, 3)
+	if len(got) != len(want) {
+		t.Fatalf("expected curve of length %d, got %v", len(want), got)
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("week %d: expected survival %f, got %f", i, want[i], got[i])
+		}
+	}
+}
+
+func TestTeamScheduleNumWeeks(t *testing.T) {
+	opponents := make([]*firestore.DocumentRef, 4)
+	tests := []struct {
+		weeks   []int
+		want    int
+		wantErr bool
+	}{
+		{nil, 4, false},
+		{[]int{0, 0, 1, 3}, 4, false},
+		{[]int{0, 0, 1, 1}, 2, false},
+		{[]int{0, 1, 2}, 0, true},
+		{[]int{0, 1, 2, 3, 4}, 0, true},
+		{[]int{0, -1, 1, 2}, 0, true},
+	}
+	for _, test := range tests {
+		got, err := TeamSchedule{Opponents: opponents, Weeks: test.weeks}.numWeeks()
+		if (err != nil) != test.wantErr {
+			t.Errorf("weeks %v: expected error %t, got %v", test.weeks, test.wantErr, err)
+		}
+		if err == nil && got != test.want {
+			t.Errorf("weeks %v: expected %d weeks, got %d", test.weeks, test.want, got)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"runtime"
 	"sort"
 	"sync"
 	"time"
@@ -57,6 +58,33 @@ type TeamSchedule struct {
 	Team              *firestore.DocumentRef   `firestore:"team"`
 	RelativeLocations []bts.RelativeLocation   `firestore:"locales"`
 	Opponents         []*firestore.DocumentRef `firestore:"opponents"`
+	Weeks             []int                    `firestore:"weeks"`
+	Kickoffs          []time.Time              `firestore:"kickoffs"`
+	Venues            []string                 `firestore:"venues"`
+	Statuses          []string                 `firestore:"statuses"`
+	Travel            []float64                `firestore:"travel"`
+	OpponentTravel    []float64                `firestore:"opponent_travel"`
+}
+
+// numWeeks calculates the number of weeks in the schedule.
+// Teams playing more than one game in a week record the week of each game, in which case there must be one week per opponent.
+func (ts TeamSchedule) numWeeks() (int, error) {
+	if len(ts.Weeks) == 0 {
+		return len(ts.Opponents), nil
+	}
+	if len(ts.Weeks) != len(ts.Opponents) {
+		return 0, fmt.Errorf("%d weeks recorded for %d opponents", len(ts.Weeks), len(ts.Opponents))
+	}
+	nWeeks := 0
+	for i, week := range ts.Weeks {
+		if week < 0 {
+			return 0, fmt.Errorf("game %d: negative week %d", i, week)
+		}
+		if week >= nWeeks {
+			nWeeks = week + 1
+		}
+	}
+	return nWeeks, nil
 }
 
 // PickerStreak is a picker's latest streak status, stored in the firestore database.
@@ -64,6 +92,37 @@ type PickerStreak struct {
 	PickTypes      []int                    `firestore:"pick_types_remaining"`
 	Picker         *firestore.DocumentRef   `firestore:"picker"`
 	RemainingTeams []*firestore.DocumentRef `firestore:"remaining"`
+	Constraints    []PickConstraint         `firestore:"constraints"`
+}
+
+// PickConstraint is a constraint on the weeks a picker may pick a team, stored in the firestore database.
+// See bts.PickConstraint for the meaning of the fields.
+// TODO: Combine with streaker
+type PickConstraint struct {
+	Must     *firestore.DocumentRef `firestore:"must"`
+	Never    *firestore.DocumentRef `firestore:"never"`
+	Week     *int                   `firestore:"week"`
+	After    *int                   `firestore:"after"`
+	Before   *int                   `firestore:"before"`
+	Opponent *firestore.DocumentRef `firestore:"opponent"`
+}
+
+// toBTS converts the constraint to refer to teams by name.
+func (pc PickConstraint) toBTS() bts.PickConstraint {
+	name := func(ref *firestore.DocumentRef) string {
+		if ref == nil {
+			return ""
+		}
+		return teamNameLookup[ref.ID]
+	}
+	return bts.PickConstraint{
+		Must:     name(pc.Must),
+		Never:    name(pc.Never),
+		Week:     pc.Week,
+		After:    pc.After,
+		Before:   pc.Before,
+		Opponent: name(pc.Opponent),
+	}
 }
 
 // Picker is a picker.  Huh.
@@ -84,19 +143,36 @@ type StreakPrediction struct {
 	CumulativeProbability float64 `firestore:"cumulative_probability"`
 	CumulativeSpread      float64 `firestore:"cumulative_spread"`
 	Weeks                 []Week  `firestore:"weeks"`
+	// ProbabilityCost is the cumulative probability of the recommended (top-ranked) streak minus that of this streak.
+	// It is never negative under the survival objective, which ranks streaks by cumulative probability,
+	// but is negative for streaks that are more likely to survive the season than the recommended streak under any other objective.
+	ProbabilityCost float64 `firestore:"probability_cost"`
+	// Score is the value of the streak under the objective used to find it.
+	Score float64 `firestore:"score"`
+	// ScoreTiebreak breaks ties between streaks with the same score.
+	ScoreTiebreak float64 `firestore:"score_tiebreak"`
+
+	// SurvivalCurve is the probability of surviving through each week of the streak.
+	SurvivalCurve []float64 `firestore:"survival_curve"`
+	// ExpectedWeeks is the expected number of weeks survived before the first loss.
+	ExpectedWeeks float64 `firestore:"expected_weeks"`
+	// LargestDropWeek is the season week in which the probability of survival drops the most.
+	LargestDropWeek int `firestore:"largest_drop_week"`
+	// LargestDrop is how much the probability of survival drops in LargestDropWeek.
+	LargestDrop float64 `firestore:"largest_drop"`
 }
 
-// ByProbDesc sorts StreakPredictions by probability and spread (descending)
-type ByProbDesc []StreakPrediction
+// ByScoreDesc sorts StreakPredictions by score and tiebreaker (descending)
+type ByScoreDesc []StreakPrediction
 
-func (a ByProbDesc) Len() int { return len(a) }
-func (a ByProbDesc) Less(i, j int) bool {
-	if a[i].CumulativeProbability == a[j].CumulativeProbability {
-		return a[i].CumulativeSpread > a[j].CumulativeSpread
+func (a ByScoreDesc) Len() int { return len(a) }
+func (a ByScoreDesc) Less(i, j int) bool {
+	if a[i].Score == a[j].Score {
+		return a[i].ScoreTiebreak > a[j].ScoreTiebreak
 	}
-	return a[i].CumulativeProbability > a[j].CumulativeProbability
+	return a[i].Score > a[j].Score
 }
-func (a ByProbDesc) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByScoreDesc) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 
 // PickerPrediction contains the collected predictions for a given user.
 type PickerPrediction struct {
@@ -116,6 +192,20 @@ type PickerPrediction struct {
 
 	PossiblePicks []StreakPrediction `firestore:"possible_picks"`
 
+	// SurvivalCurve is the probability of surviving through each week of the best streak.
+	SurvivalCurve []float64 `firestore:"survival_curve"`
+	// ExpectedWeeks is the expected number of weeks the best streak survives before the first loss.
+	ExpectedWeeks float64 `firestore:"expected_weeks"`
+	// LargestDropWeek is the season week in which the probability of surviving the best streak drops the most.
+	LargestDropWeek int `firestore:"largest_drop_week"`
+	// LargestDrop is how much the probability of surviving the best streak drops in LargestDropWeek.
+	LargestDrop float64 `firestore:"largest_drop"`
+
+	// Objective is the name of the objective the streaks were scored by.
+	Objective string `firestore:"objective"`
+	// Opponents are the pickers the streaks were scored against, if any.
+	Opponents []*firestore.DocumentRef `firestore:"opponents"`
+
 	// CalculationStartTime is when the program that produced the results started
 	CalculationStartTime time.Time `firestore:"calculation_start_time"`
 	// CalculationEndTime is when the results were generated and finalized
@@ -124,6 +214,9 @@ type PickerPrediction struct {
 
 var teamRefLookup = make(map[string]*firestore.DocumentRef)
 
+// teamNameLookup maps team document IDs to the team's name_4.
+var teamNameLookup = make(map[string]string)
+
 func makeTeamLookup(ctx context.Context, fs *firestore.Client) error {
 	teamIter := fs.Collection("teams").Documents(ctx)
 	defer teamIter.Stop()
@@ -142,12 +235,20 @@ func makeTeamLookup(ctx context.Context, fs *firestore.Client) error {
 		}
 
 		teamRefLookup[team4.(string)] = teamDoc.Ref
+		teamNameLookup[teamDoc.Ref.ID] = team4.(string)
 	}
 	return nil
 }
 
+// pickerRefLookup looks up a picker by the name Luke has given them, falling back to their aliases.
 func pickerRefLookup(ctx context.Context, fs *firestore.Client, name string) (*firestore.DocumentRef, error) {
 	pickerRef, err := fs.Collection("pickers").Where("name_luke", "==", name).Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		pickerRef, err = fs.Collection("pickers").Where("aliases", "array-contains", name).Limit(1).Documents(ctx).Next()
+	}
+	if err == iterator.Done {
+		return nil, fmt.Errorf("luke name \"%s\" not in pickers", name)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +263,15 @@ var tE = flag.Float64("te", 3., "Simulated annealing temperature exponent: p = (
 var resetItr = flag.Int("reseti", 10000, "Maximum number of iterations to allow simulated annealing solution to wonder before resetting to best solution found so far.")
 var seed = flag.Int64("seed", -1, "Seed for RNG governing simulated annealing process. Negative values will use system clock to seed RNG.")
 var workers = flag.Int("workers", 1, "Number of workers per simulated picker. Increases odds of finding the global maximum.")
+var exhaustive = flag.Bool("exhaustive", false, "Search every possible streak instead of simulated annealing. Only feasible for pickers with few teams remaining.")
+var shardsFlag = flag.Int("shards", runtime.NumCPU(), "Number of concurrent shards to split an exhaustive search into.")
+var topK = flag.Int("topk", 10, "Number of distinct best streaks to keep and report for each picker.")
+var objectiveFlag = flag.String("objective", "survival", "Objective to maximize: survival (probability of surviving every week), weeks (expected weeks survived), minweek (smallest single-week probability), or outlast (probability of surviving longer than the pickers given by -opponents).")
+var opponentsFlag = flag.String("opponents", "", "Comma-separated names of pickers to outlast with the outlast objective.")
+var poolFlag = flag.Bool("pool", false, "Recommend the streaks that maximize the probability of being the last survivor among every picker in the pool.")
+var poolItr = flag.Int("pooli", 100000, "Number of seasons to simulate when calculating the probability of winning the pool.")
+var travelFlag = flag.Bool("travel", false, "Scale the home-field bias by how much farther each team travels to the game, using the travel distances stored with the schedule. Games without travel distances get a close bias of half the home bias.")
+var rulesFlag = flag.String("rules", "", "Pool rules for scoring picks on games that are not played as scheduled, as comma-separated status=rule pairs, where status is postponed, cancelled, or no-contest, and rule is refund, win, or loss (e.g., \"cancelled=win,no-contest=loss\"). Unlisted statuses are refunded.")
 
 func mockRequest(picker string, week *int) (*httptest.ResponseRecorder, *http.Request) {
 	rm := RequestMessage{Picker: picker, Week: week}
@@ -221,6 +331,60 @@ func main() {
 type RequestMessage struct {
 	Picker string `json:"picker"`
 	Week   *int   `json:"week"` // pointer, as this is optional, but could be zero
+
+	// Exhaustive requests a search of every possible streak rather than simulated annealing.
+	Exhaustive bool `json:"exhaustive,omitempty"`
+	// Shards is the number of shards an exhaustive search is split into.
+	Shards int `json:"shards,omitempty"`
+	// Shard, if given, limits an exhaustive search to a single shard, the results of which are stored for a later reduce request.
+	Shard *int `json:"shard,omitempty"` // pointer, as this is optional, but could be zero
+	// Job identifies the shards that belong together.
+	Job string `json:"job,omitempty"`
+	// Reduce requests that the stored shards of the job be merged into a prediction.
+	Reduce bool `json:"reduce,omitempty"`
+
+	// Objective is the name of the objective to maximize. See bts.MakeObjective.
+	Objective string `json:"objective,omitempty"`
+	// Opponents are the names of pickers to outlast with the outlast objective.
+	Opponents []string `json:"opponents,omitempty"`
+
+	// Pool requests streaks that maximize the probability of being the last survivor in the pool.
+	Pool bool `json:"pool,omitempty"`
+
+	// Rules are the pool's rules for scoring picks on games that are not played as scheduled. See bts.ParsePoolRules.
+	Rules string `json:"rules,omitempty"`
+}
+
+func (rm RequestMessage) validate() error {
+	if rm.Shard != nil {
+		if rm.Job == "" {
+			return fmt.Errorf("shard %d requested without a job", *rm.Shard)
+		}
+		if *rm.Shard < 0 || *rm.Shard >= rm.Shards {
+			return fmt.Errorf("shard %d out of range [0,%d)", *rm.Shard, rm.Shards)
+		}
+	}
+	if rm.Reduce && rm.Job == "" {
+		return fmt.Errorf("reduce requested without a job")
+	}
+	if rm.Reduce && rm.Shard != nil {
+		return fmt.Errorf("cannot both reduce and search shard %d", *rm.Shard)
+	}
+	if rm.Objective != "" && rm.Objective != "outlast" {
+		if _, err := bts.MakeObjective(rm.Objective, nil); err != nil {
+			return err
+		}
+	}
+	if rm.Pool && (rm.Shard != nil || rm.Reduce || rm.Exhaustive) {
+		return fmt.Errorf("pool simulation cannot be combined with exhaustive search")
+	}
+	if len(rm.Opponents) > 0 && rm.Objective != "outlast" {
+		return fmt.Errorf("opponents given for objective %s", rm.Objective)
+	}
+	if _, err := bts.ParsePoolRules(rm.Rules); err != nil {
+		return err
+	}
+	return nil
 }
 
 // InnerMessage is the inner payload of a Pub/Sub event.
@@ -253,6 +417,10 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	if check(w, err, http.StatusBadRequest) {
 		return
 	}
+	err = rm.validate()
+	if check(w, err, http.StatusBadRequest) {
+		return
+	}
 
 	log.Printf("Beating the streak, picker %s", rm.Picker)
 	weekNumber := rm.Week
@@ -290,26 +458,6 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	iter.Stop()
 	log.Printf("latest sagarin ratings discovered: %s", sagRateDoc.Ref.ID)
 
-	// With these in hand, calculate the week number if necessary
-	if weekNumber == nil {
-		seasonStart, err := seasonDoc.DataAt("start")
-		if check(w, err, http.StatusInternalServerError) {
-			return
-		}
-
-		sagtime, err := sagRateDoc.DataAt("timestamp")
-		if check(w, err, http.StatusInternalServerError) {
-			return
-		}
-
-		weekTime := sagtime.(time.Time).Sub(seasonStart.(time.Time))
-		week := int(weekTime.Hours() / (24 * 7))
-		weekNumber = &week
-		log.Printf("Determined week number %d from Sagarin and season start", *weekNumber)
-	} else {
-		log.Printf("Week number given as %d", *weekNumber)
-	}
-
 	// Get this user
 	pickerRef, err := pickerRefLookup(ctx, fs, pickerName)
 	if check(w, err, http.StatusInternalServerError) {
@@ -400,18 +548,27 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	homeBias := sagPerf.HomeBias + homeAdvantage.(float64)
 	closeBias := homeBias / 2.
 	model := bts.NewGaussianSpreadModel(ratingsMap, sagPerf.StandardDeviation, homeBias, closeBias)
+	if *travelFlag {
+		model = bts.NewTravelSpreadModel(ratingsMap, sagPerf.StandardDeviation, homeBias)
+	}
 
 	log.Printf("Built model %v", model)
 
 	// log.Printf("Most recent season %v", seasonDoc)
 
-	// Get schedule from most recent season
-	iter = fs.Collection("schedules").Where("season", "==", seasonDoc.Ref).Limit(1).Documents(ctx)
+	// Get the newest revision of the schedule from most recent season
+	iter = fs.Collection("schedules").Where("season", "==", seasonDoc.Ref).OrderBy("timestamp", firestore.Desc).Limit(1).Documents(ctx)
 	scheduleDoc, err := iter.Next()
+	iter.Stop()
+	if err == iterator.Done {
+		// Schedules stored without a timestamp are only found unordered.
+		iter = fs.Collection("schedules").Where("season", "==", seasonDoc.Ref).Limit(1).Documents(ctx)
+		scheduleDoc, err = iter.Next()
+		iter.Stop()
+	}
 	if check(w, err, http.StatusInternalServerError) {
 		return
 	}
-	iter.Stop()
 
 	// log.Printf("Most recent schedule %v", scheduleDoc)
 
@@ -449,7 +606,17 @@ func handler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		schedule[team] = make([]*bts.Game, len(opponentDocs))
+		nWeeks, err := ts.numWeeks()
+		if err != nil {
+			err = fmt.Errorf("team %s: %v", team.Name(), err)
+		}
+		if check(w, err, http.StatusInternalServerError) {
+			return
+		}
+		schedule[team] = make([][]*bts.Game, nWeeks)
+		for i := range schedule[team] {
+			schedule[team][i] = make([]*bts.Game, 0, 1)
+		}
 		for i, opponent := range opponentDocs {
 			var op bts.Team
 			err = opponent.DataTo(&op)
@@ -458,8 +625,31 @@ func handler(w http.ResponseWriter, r *http.Request) {
 			}
 
 			game := bts.NewGame(team, op, ts.RelativeLocations[i])
+			if i < len(ts.Kickoffs) {
+				game.SetKickoff(ts.Kickoffs[i])
+			}
+			if i < len(ts.Venues) {
+				game.SetVenue(ts.Venues[i])
+			}
+			if i < len(ts.Statuses) {
+				status, err := bts.ParseGameStatus(ts.Statuses[i])
+				if check(w, err, http.StatusInternalServerError) {
+					return
+				}
+				game.SetStatus(status)
+			}
+			if i < len(ts.Travel) && i < len(ts.OpponentTravel) && ts.Travel[i] >= 0 && ts.OpponentTravel[i] >= 0 {
+				game.SetTravel(ts.Travel[i], ts.OpponentTravel[i])
+			}
 			//log.Printf("game loaded %v", game)
-			schedule[team][i] = game
+			if op == bts.BYE {
+				continue
+			}
+			week := i
+			if i < len(ts.Weeks) {
+				week = ts.Weeks[i]
+			}
+			schedule[team][week] = append(schedule[team][week], game)
 
 		}
 	}
@@ -467,7 +657,44 @@ func handler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Schedule built:\n%v", schedule)
 
-	predictions := bts.MakePredictions(&schedule, *model)
+	// With the schedule in hand, calculate the week number if necessary
+	if weekNumber == nil {
+		sagtime, err := sagRateDoc.DataAt("timestamp")
+		if check(w, err, http.StatusInternalServerError) {
+			return
+		}
+
+		week, ok := schedule.WeekOf(sagtime.(time.Time))
+		if ok {
+			log.Printf("Determined week number %d from Sagarin and game kickoff times", week)
+		} else {
+			// Schedules stored without kickoff times can only count weeks from the start of the season.
+			seasonStart, err := seasonDoc.DataAt("start")
+			if check(w, err, http.StatusInternalServerError) {
+				return
+			}
+
+			weekTime := sagtime.(time.Time).Sub(seasonStart.(time.Time))
+			week = int(weekTime.Hours() / (24 * 7))
+			log.Printf("Determined week number %d from Sagarin and season start", week)
+		}
+		weekNumber = &week
+	} else {
+		log.Printf("Week number given as %d", *weekNumber)
+	}
+
+	rulesString := *rulesFlag
+	if rm.Rules != "" {
+		rulesString = rm.Rules
+	}
+	rules, err := bts.ParsePoolRules(rulesString)
+	if check(w, err, http.StatusBadRequest) {
+		return
+	}
+	log.Printf("Scoring picks on games not played as scheduled by pool rules %s", rules)
+
+	predictionModel := rules.Model(*model)
+	predictions := bts.MakePredictions(&schedule, predictionModel)
 	log.Printf("Made predictions\n%s", predictions)
 
 	// Get picker remaining teams
@@ -524,7 +751,7 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		remainingTeams[i] = team
 	}
 
-	players[p.Name], err = bts.NewPlayer(p.Name, remainingTeams, ps.PickTypes)
+	players[p.Name], err = bts.NewPlayer(p.Name, remainingTeams, ps.PickTypes, schedule.NumWeeks()-*weekNumber)
 	if check(w, err, http.StatusInternalServerError) {
 		return
 	}
@@ -537,6 +764,31 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	predictions.FilterWeeks(*weekNumber)
 	log.Printf("Filtered predictions:\n%s", predictions)
 
+	// Constrain the weeks each team can be picked
+	constraints := make([]bts.PickConstraint, len(ps.Constraints))
+	for i, pc := range ps.Constraints {
+		constraints[i] = pc.toBTS()
+	}
+	err = players[p.Name].Constrain(&schedule, constraints, *weekNumber)
+	if check(w, err, http.StatusInternalServerError) {
+		return
+	}
+	log.Printf("Picker constrained by %v", constraints)
+	err = players[p.Name].ApplyRules(&schedule, rules)
+	if check(w, err, http.StatusInternalServerError) {
+		return
+	}
+
+	alive, err := players[p.Name].Alive(ctx, predictions)
+	if check(w, err, http.StatusInternalServerError) {
+		return
+	}
+	if !alive {
+		log.Printf("Picker %s cannot survive the remaining weeks under pool rules %s", p.Name, rules)
+		http.Error(w, fmt.Sprintf("picker %s has been eliminated", p.Name), http.StatusOK)
+		return
+	}
+
 	// Here we go.
 	// Find the unique users.
 	// Legacy code!
@@ -553,24 +805,91 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	log.Println("Starting MC")
+	shards := *shardsFlag
+	if rm.Shards > 0 {
+		shards = rm.Shards
+	}
+
+	objectiveName := *objectiveFlag
+	if rm.Objective != "" {
+		objectiveName = rm.Objective
+	}
+	opponentNames := splitNames(*opponentsFlag)
+	if len(rm.Opponents) > 0 {
+		opponentNames = rm.Opponents
+	}
+	obj, opponentRefs, err := makeObjective(ctx, fs, objectiveName, opponentNames, seasonDoc.Ref, *weekNumber)
+	if check(w, err, http.StatusInternalServerError) {
+		return
+	}
+	log.Printf("Maximizing objective %s", objectiveName)
+
+	var streakOptions map[string]PickerPrediction
+	switch {
+	case rm.Reduce:
+		streakOptions, err = reduceShards(ctx, fs, rm.Job, p.Name, ps.Picker, *weekNumber, *topK)
+		if check(w, err, http.StatusInternalServerError) {
+			return
+		}
+
+	case rm.Shard != nil:
+		err = searchShard(ctx, fs, rm, ps.Picker, players[p.Name], predictions, obj, *weekNumber, *topK)
+		if check(w, err, http.StatusInternalServerError) {
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusOK), http.StatusOK)
+		return
+
+	case rm.Pool || *poolFlag:
+		log.Println("Starting pool simulation")
+
+		opponents, err := loadOpponents(ctx, fs, picksDoc.Ref, ps.Picker, &schedule, predictions, rules, *weekNumber)
+		if check(w, err, http.StatusInternalServerError) {
+			return
+		}
+
+		bestStreaks, err := poolStreaks(ctx, players, opponents, &schedule, predictionModel, predictions, obj, *poolItr)
+		if check(w, err, http.StatusInternalServerError) {
+			return
+		}
+		objectiveName = "pool"
+
+		// Collect by player
+		streakOptions = collectByPlayer(bestStreaks, players, predictions, &schedule, *weekNumber)
+
+	case rm.Exhaustive || *exhaustive:
+		log.Println("Starting exhaustive search")
+
+		bestStreaks, err := exhaustiveStreaks(ctx, players, predictions, obj, shards, *topK)
+		if check(w, err, http.StatusInternalServerError) {
+			return
+		}
+
+		// Collect by player
+		streakOptions = collectByPlayer(bestStreaks, players, predictions, &schedule, *weekNumber)
 
-	// Loop through the unique users
-	playerItr := playerIterator(players)
+	default:
+		log.Println("Starting MC")
 
-	// Loop through streaks
-	ppts := perPlayerTeamStreaks(playerItr, predictions)
+		// Loop through the unique users
+		playerItr := playerIterator(players)
 
-	// Update best
-	bestStreaks := calculateBestStreaks(ppts)
+		// Loop through streaks
+		pss := perPlayerStreaks(playerItr, predictions, obj)
 
-	// Collect by player
-	streakOptions := collectByPlayer(bestStreaks, players, predictions, &schedule, *weekNumber)
+		// Update best
+		bestStreaks := calculateBestStreaks(pss)
+
+		// Collect by player
+		streakOptions = collectByPlayer(bestStreaks, players, predictions, &schedule, *weekNumber)
+	}
 
 	// Print results
 	output := fs.Collection("streak_predictions")
 	// Note: only one picker for now!
 	for _, streak := range streakOptions {
+		streak.Objective = objectiveName
+		streak.Opponents = opponentRefs
 		streak.Picker = ps.Picker
 		streak.Remaining = ps.RemainingTeams
 		streak.PickTypes = ps.PickTypes
@@ -592,52 +911,20 @@ func handler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// StreakMap is a simple map of player names to streaks
-type streakMap map[playerTeam]streakProb
-
-type streakProb struct {
-	streak *bts.Streak
-	prob   float64
-	spread float64
-}
-
-type playerTeam struct {
-	player string
-	team   bts.Team
-}
+// streakMap is a simple map of player names to the best distinct streaks found for that player.
+type streakMap map[string]*bts.TopStreaks
 
-func (sm *streakMap) update(player string, team bts.Team, spin streakProb) {
-	pt := playerTeam{player: player, team: team}
-	bestp := math.Inf(-1)
-	bests := math.Inf(-1)
-	if sp, ok := (*sm)[pt]; ok {
-		bestp = sp.prob
-		bests = sp.spread
-	}
-	if spin.prob > bestp || (spin.prob == bestp && spin.spread > bests) {
-		(*sm)[pt] = streakProb{streak: spin.streak, prob: spin.prob, spread: spin.spread}
+// merge merges streaks found for a player into the map, keeping only the best k.
+func (sm streakMap) merge(player string, top *bts.TopStreaks, k int) {
+	if _, ok := sm[player]; !ok {
+		sm[player] = bts.NewTopStreaks(k)
 	}
+	sm[player].Merge(top)
 }
 
-// func (sm streakMap) getBest(player string) streakProb {
-// 	bestp := math.Inf(-1)
-// 	bests := math.Inf(-1)
-// 	bestt := bts.BYE
-// 	for pt, sp := range sm {
-// 		if pt.player != player {
-// 			continue
-// 		}
-// 		if sp.prob > bestp || (sp.prob == bestp && sp.spread > bests) {
-// 			bestt = pt.team
-// 		}
-// 	}
-// 	return sm[playerTeam{player: player, team: bestt}]
-// }
-
-type playerTeamStreakProb struct {
-	player     *bts.Player
-	team       bts.Team
-	streakProb streakProb
+type playerStreaks struct {
+	player  *bts.Player
+	streaks *bts.TopStreaks
 }
 
 func playerIterator(pm bts.PlayerMap) <-chan *bts.Player {
@@ -654,11 +941,11 @@ func playerIterator(pm bts.PlayerMap) <-chan *bts.Player {
 	return out
 }
 
-func perPlayerTeamStreaks(ps <-chan *bts.Player, predictions *bts.Predictions) <-chan playerTeamStreakProb {
+func perPlayerStreaks(ps <-chan *bts.Player, predictions *bts.Predictions, obj bts.Objective) <-chan playerStreaks {
 
-	out := make(chan playerTeamStreakProb, 100)
+	out := make(chan playerStreaks, 100)
 
-	go func(out chan<- playerTeamStreakProb) {
+	go func(out chan<- playerStreaks) {
 		var wg sync.WaitGroup
 		sd := *seed
 		if sd < 0 {
@@ -669,8 +956,8 @@ func perPlayerTeamStreaks(ps <-chan *bts.Player, predictions *bts.Predictions) <
 			for i := 0; i < *workers; i++ {
 				wg.Add(1)
 				mySeed := src.Int63()
-				go func(p *bts.Player, out chan<- playerTeamStreakProb) {
-					anneal(mySeed, p, predictions, out)
+				go func(p *bts.Player, out chan<- playerStreaks) {
+					out <- playerStreaks{player: p, streaks: anneal(mySeed, p, predictions, obj)}
 					wg.Done()
 				}(p, out)
 			}
@@ -682,7 +969,9 @@ func perPlayerTeamStreaks(ps <-chan *bts.Player, predictions *bts.Predictions) <
 	return out
 }
 
-func anneal(seed int64, p *bts.Player, predictions *bts.Predictions, out chan<- playerTeamStreakProb) {
+// anneal searches for the best streaks of a player under the objective using simulated annealing.
+// Every possible streak visited is considered for inclusion in the returned top streaks.
+func anneal(seed int64, p *bts.Player, predictions *bts.Predictions, obj bts.Objective) *bts.TopStreaks {
 
 	src := rand.NewSource(seed)
 	rng := rand.New(src)
@@ -693,74 +982,83 @@ func anneal(seed int64, p *bts.Player, predictions *bts.Predictions, out chan<-
 	maxDrift := *resetItr
 	countSinceReset := maxDrift
 
-	s := bts.NewStreak(p.RemainingTeams(), <-p.WeekTypeIterator())
+	top := bts.NewTopStreaks(*topK)
+
+	// Start from a streak that satisfies the picker's constraints.
+	s, err := p.FeasibleStreak(context.Background())
+	if err != nil {
+		log.Printf("Player %s: %v", p.Name(), err)
+		return top
+	}
+	avail := p.Availability()
 	bestS := s.Clone()
 	resetS := s.Clone()
-	bestP := 0.
-	resetP := 0.
-	bestSpread := 0.
-	resetSpread := 0.
+	bestScore := bts.Score{Value: math.Inf(-1), Tiebreak: math.Inf(-1)}
+	resetScore := bestScore
+	var weekProbs []float64
 
-	log.Printf("Player %s start: p=%f, s=%f, streak=%s", p.Name(), bestP, bestSpread, bestS)
+	log.Printf("Player %s start: streak=%s", p.Name(), bestS)
 	for i := 0; i < maxIterations; i++ {
 		temperature := tConst * float64(maxIterations-i) / float64(maxIterations)
 		temperature = math.Pow(temperature, tExp)
 
-		s.Perturbate(src, true)
-		newP, newSpread := bts.SummarizeStreak(predictions, s)
+		s.PerturbateAvailable(src, true, avail)
+		var newSpread float64
+		weekProbs, newSpread = bts.WeekProbabilities(predictions, s, weekProbs)
+		newP := 1.
+		for _, wp := range weekProbs {
+			newP *= wp
+		}
 
 		// ignore impossible outcomes
 		if newP == 0 {
 			continue
 		}
 
-		if newP > bestP || (newP == bestP && newSpread > bestSpread) || (bestP-newP)*temperature > rng.Float64() {
+		newScore := obj.Score(weekProbs, newSpread)
+		top.PushClone(s, newScore, newP, newSpread)
+
+		if newScore.Better(bestScore) || (bestScore.Value-newScore.Value)*temperature > rng.Float64() {
 
-			// if newP <= bestP {
+			// if !newScore.Better(bestScore) {
 			// 	log.Printf("Player %s accepted worse outcome due to temperature", p.Name())
 			// }
 
-			bestP = newP
-			bestSpread = newSpread
+			bestScore = newScore
 			bestS = s.Clone()
 
-			if bestP > resetP {
-				resetP = bestP
-				resetSpread = bestSpread
+			if bestScore.Better(resetScore) {
+				resetScore = bestScore
 				resetS = bestS.Clone()
 				countSinceReset = maxDrift
 
-				for _, team := range resetS.GetWeek(0) {
-					sp := streakProb{streak: resetS.Clone(), prob: resetP, spread: resetSpread}
-					out <- playerTeamStreakProb{player: p, team: team, streakProb: sp}
-				}
-
-				log.Printf("Player %s itr %d (temp %f): p=%f, s=%f, streak=%s", p.Name(), i, temperature, bestP, bestSpread, bestS)
+				log.Printf("Player %s itr %d (temp %f): p=%f, s=%f, score=%v, streak=%s", p.Name(), i, temperature, newP, newSpread, bestScore, bestS)
 			}
 
 		} else if countSinceReset < 0 {
 			countSinceReset = maxDrift
-			bestP = resetP
-			bestSpread = resetSpread
+			bestScore = resetScore
 			// bestS = resetS.Clone()
 			s = resetS.Clone()
 
-			// log.Printf("Player %s reset at itr %d to p=%f, s=%f, streak=%s", p.Name(), i, bestP, bestSpread, bestS)
+			// log.Printf("Player %s reset at itr %d to score=%v, streak=%s", p.Name(), i, bestScore, bestS)
 		}
 
 		countSinceReset--
 	}
+
+	return top
 }
 
-func calculateBestStreaks(ppts <-chan playerTeamStreakProb) <-chan streakMap {
+func calculateBestStreaks(pss <-chan playerStreaks) <-chan streakMap {
 	out := make(chan streakMap, 100)
 
 	sm := make(streakMap)
 	go func() {
 		defer close(out)
 
-		for ptsp := range ppts {
-			sm.update(ptsp.player.Name(), ptsp.team, ptsp.streakProb)
+		for ps := range pss {
+			sm.merge(ps.player.Name(), ps.streaks, *topK)
 		}
 
 		out <- sm
@@ -777,35 +1075,11 @@ func collectByPlayer(sms <-chan streakMap, players bts.PlayerMap, predictions *b
 	soByPlayer := make(map[string][]StreakPrediction)
 	for sm := range sms {
 
-		for pt, sp := range sm {
-
-			prob := sp.prob
-			spread := sp.spread
-
-			weeks := make([]Week, sp.streak.NumWeeks())
-			for iweek := 0; iweek < sp.streak.NumWeeks(); iweek++ {
-
-				seasonWeek := iweek + weekNumber
-				pickedTeams := make([]*firestore.DocumentRef, 0)
-				pickedProbs := make([]float64, 0)
-				pickedSpreads := make([]float64, 0)
-				for _, team := range sp.streak.GetWeek(iweek) {
-					probability := predictions.GetProbability(team, iweek)
-					pickedProbs = append(pickedProbs, probability)
-
-					spread := predictions.GetSpread(team, iweek)
-					pickedSpreads = append(pickedSpreads, spread)
-
-					// opponent := schedule.Get(team, iweek).Team(1)
-					pickedTeams = append(pickedTeams, teamRefLookup[team.Name()])
-				}
-
-				weeks[iweek] = Week{WeekNumber: seasonWeek, Pick: pickedTeams, Probabilities: pickedProbs, Spreads: pickedSpreads}
-
+		for player, top := range sm {
+			for _, r := range top.Results() {
+				so := makeStreakPrediction(r, predictions, weekNumber)
+				soByPlayer[player] = append(soByPlayer[player], so)
 			}
-
-			so := StreakPrediction{CumulativeProbability: prob, CumulativeSpread: spread, Weeks: weeks}
-			soByPlayer[pt.player] = append(soByPlayer[pt.player], so)
 		}
 
 	}
@@ -819,33 +1093,96 @@ func collectByPlayer(sms <-chan streakMap, players bts.PlayerMap, predictions *b
 			continue
 		}
 
-		sort.Sort(ByProbDesc(streakOptions))
+		prs[picker] = makePickerPrediction(streakOptions, weekNumber, startTime)
+	}
 
-		bestSelection := streakOptions[0].Weeks[0].Pick
-		bestProb := streakOptions[0].CumulativeProbability
-		bestSpread := streakOptions[0].CumulativeSpread
+	return prs
+}
+
+// makeStreakPrediction converts a streak into the format stored in Firestore.
+// Teams picked in the same week are stored in canonical (sorted) order.
+func makeStreakPrediction(r bts.StreakResult, predictions *bts.Predictions, weekNumber int) StreakPrediction {
+	streak := r.Streak.Canonical()
+	weeks := make([]Week, streak.NumWeeks())
+	for iweek := 0; iweek < streak.NumWeeks(); iweek++ {
+
+		seasonWeek := iweek + weekNumber
+		pickedTeams := make([]*firestore.DocumentRef, 0)
+		pickedProbs := make([]float64, 0)
+		pickedSpreads := make([]float64, 0)
+		for _, team := range streak.GetWeek(iweek) {
+			if team == bts.NONE {
+				// Weeks without picks are stored without picks
+				continue
+			}
 
-		prs[picker] = PickerPrediction{
-			// Picker            *firestore.DocumentRef `firestore:"picker"`
-			// Season            *firestore.DocumentRef `firestore:"season"`
-			Week: weekNumber,
-			// Schedule          *firestore.DocumentRef `firestore:"schedule"`
-			// Sagarin           *firestore.DocumentRef `firestore:"sagarin"`
-			// PredictionTracker *firestore.DocumentRef `firestore:"prediction_tracker"`
+			probability := predictions.GetProbability(team, iweek)
+			pickedProbs = append(pickedProbs, probability)
 
-			// Remaining []*firestore.DocumentRef `firestore:"remaining"`
-			// PickTypes []int                    `firestore:"pick_types_remaining"`
+			spread := predictions.GetSpread(team, iweek)
+			pickedSpreads = append(pickedSpreads, spread)
 
-			BestPick:             bestSelection,
-			Probability:          bestProb,
-			Spread:               bestSpread,
-			PossiblePicks:        streakOptions,
-			CalculationStartTime: startTime,
-			CalculationEndTime:   time.Now(),
+			// opponent := schedule.Get(team, iweek).Team(1)
+			pickedTeams = append(pickedTeams, teamRefLookup[team.Name()])
 		}
+
+		weeks[iweek] = Week{WeekNumber: seasonWeek, Pick: pickedTeams, Probabilities: pickedProbs, Spreads: pickedSpreads}
+
 	}
 
-	return prs
+	survival := bts.SummarizeSurvival(predictions, streak)
+
+	return StreakPrediction{
+		CumulativeProbability: r.Probability,
+		CumulativeSpread:      r.Spread,
+		Weeks:                 weeks,
+		Score:                 r.Score.Value,
+		ScoreTiebreak:         r.Score.Tiebreak,
+		SurvivalCurve:         survival.Curve,
+		ExpectedWeeks:         survival.ExpectedWeeks,
+		LargestDropWeek:       survival.LargestDropWeek + weekNumber,
+		LargestDrop:           survival.LargestDrop,
+	}
+}
+
+// makePickerPrediction sorts the streak options by score, calculates how much probability each option costs relative to the top-ranked option, and summarizes the top-ranked one.
+// See StreakPrediction.ProbabilityCost for why the cost can be negative.
+func makePickerPrediction(streakOptions []StreakPrediction, weekNumber int, startTime time.Time) PickerPrediction {
+	sort.Sort(ByScoreDesc(streakOptions))
+
+	bestSelection := streakOptions[0].Weeks[0].Pick
+	bestProb := streakOptions[0].CumulativeProbability
+	bestSpread := streakOptions[0].CumulativeSpread
+
+	for i := range streakOptions {
+		streakOptions[i].ProbabilityCost = bestProb - streakOptions[i].CumulativeProbability
+	}
+
+	best := streakOptions[0]
+	log.Printf("Best streak survives %f weeks on average, with the largest drop (%f) in week %d", best.ExpectedWeeks, best.LargestDrop, best.LargestDropWeek)
+
+	return PickerPrediction{
+		// Picker            *firestore.DocumentRef `firestore:"picker"`
+		// Season            *firestore.DocumentRef `firestore:"season"`
+		Week: weekNumber,
+		// Schedule          *firestore.DocumentRef `firestore:"schedule"`
+		// Sagarin           *firestore.DocumentRef `firestore:"sagarin"`
+		// PredictionTracker *firestore.DocumentRef `firestore:"prediction_tracker"`
+
+		// Remaining []*firestore.DocumentRef `firestore:"remaining"`
+		// PickTypes []int                    `firestore:"pick_types_remaining"`
+
+		BestPick:             bestSelection,
+		Probability:          bestProb,
+		Spread:               bestSpread,
+		PossiblePicks:        streakOptions,
+		SurvivalCurve:        best.SurvivalCurve,
+		ExpectedWeeks:        best.ExpectedWeeks,
+		LargestDropWeek:      best.LargestDropWeek,
+		LargestDrop:          best.LargestDrop,
+		CalculationStartTime: startTime,
+		CalculationEndTime:   time.Now(),
+	}
 }
 
 // func determineWeekNumber(players bts.PlayerMap, schedule *bts.Schedule) int {
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+)
+
+// splitNames splits a comma-separated list of names, ignoring empty names.
+func splitNames(s string) []string {
+	out := make([]string, 0)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// makeObjective builds the named objective.
+// The outlast objective is built from the survival curves of the most recent best streak predicted for each opponent this week,
+// and the references of those opponents are returned alongside it.
+func makeObjective(ctx context.Context, fs *firestore.Client, name string, opponents []string, season *firestore.DocumentRef, weekNumber int) (bts.Objective, []*firestore.DocumentRef, error) {
+	if name != "outlast" {
+		obj, err := bts.MakeObjective(name, nil)
+		return obj, nil, err
+	}
+
+	curves := make([][]float64, 0, len(opponents))
+	refs := make([]*firestore.DocumentRef, 0, len(opponents))
+	for _, opponent := range opponents {
+		ref, err := pickerRefLookup(ctx, fs, opponent)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opponent %s: %v", opponent, err)
+		}
+		curve, err := opponentSurvival(ctx, fs, ref, season, weekNumber)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opponent %s: %v", opponent, err)
+		}
+		curves = append(curves, curve)
+		refs = append(refs, ref)
+	}
+
+	obj, err := bts.MakeObjective(name, curves)
+	return obj, refs, err
+}
+
+// opponentSurvival reads the most recent prediction for a picker this week and returns the survival curve of its best streak.
+func opponentSurvival(ctx context.Context, fs *firestore.Client, picker *firestore.DocumentRef, season *firestore.DocumentRef, weekNumber int) ([]float64, error) {
+	doc, err := fs.Collection("streak_predictions").Where("picker", "==", picker).Where("season", "==", season).Where("week", "==", weekNumber).OrderBy("calculation_end_time", firestore.Desc).Limit(1).Documents(ctx).Next()
+	if err != nil {
+		return nil, fmt.Errorf("no streak prediction for week %d: %v", weekNumber, err)
+	}
+	var pp PickerPrediction
+	if err := doc.DataTo(&pp); err != nil {
+		return nil, err
+	}
+	if len(pp.PossiblePicks) == 0 {
+		return nil, fmt.Errorf("streak prediction %s has no possible picks", doc.Ref.ID)
+	}
+	if len(pp.SurvivalCurve) > 0 {
+		return pp.SurvivalCurve, nil
+	}
+	return survivalCurve(pp.PossiblePicks[0], weekNumber), nil
+}
+
+// survivalCurve calculates the probability of surviving a streak through each week, starting with the given season week.
+// Weeks without picks are survived with certainty.
+func survivalCurve(sp StreakPrediction, weekNumber int) []float64 {
+	weeks := make([]bts.PickProbabilities, len(sp.Weeks))
+	for i, week := range sp.Weeks {
+		weeks[i] = bts.PickProbabilities{Week: week.WeekNumber, Probabilities: week.Probabilities}
+	}
+	return bts.SummarizeWeeks(bts.PickedWeekProbabilities(weeks, weekNumber)).Curve
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"google.golang.org/api/iterator"
+)
+
+// loadOpponents loads every other picker in the pool who is still alive under the pool rules from the streaks of a teams remaining document.
+// The schedule and predictions are expected to be filtered so that their first week is the season week weekNumber.
+func loadOpponents(ctx context.Context, fs *firestore.Client, picksRef *firestore.DocumentRef, exclude *firestore.DocumentRef, schedule *bts.Schedule, predictions *bts.Predictions, rules bts.PoolRules, weekNumber int) (bts.PlayerMap, error) {
+	opponents := make(bts.PlayerMap)
+
+	iter := picksRef.Collection("streaks").Documents(ctx)
+	defer iter.Stop()
+	for {
+		pickDoc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var ps PickerStreak
+		if err := pickDoc.DataTo(&ps); err != nil {
+			return nil, err
+		}
+		if ps.Picker.ID == exclude.ID {
+			continue
+		}
+
+		pickerDoc, err := ps.Picker.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var p Picker
+		if err := pickerDoc.DataTo(&p); err != nil {
+			return nil, err
+		}
+
+		remaining := make(bts.Remaining, len(ps.RemainingTeams))
+		for i, ref := range ps.RemainingTeams {
+			remaining[i] = bts.Team{Name4: teamNameLookup[ref.ID]}
+		}
+		player, err := bts.NewPlayer(p.Name, remaining, ps.PickTypes, schedule.NumWeeks())
+		if err != nil {
+			return nil, err
+		}
+
+		constraints := make([]bts.PickConstraint, len(ps.Constraints))
+		for i, pc := range ps.Constraints {
+			constraints[i] = pc.toBTS()
+		}
+		if err := player.Constrain(schedule, constraints, weekNumber); err != nil {
+			return nil, err
+		}
+		if err := player.ApplyRules(schedule, rules); err != nil {
+			return nil, err
+		}
+
+		alive, err := player.Alive(ctx, predictions)
+		if err != nil {
+			return nil, err
+		}
+		if !alive {
+			log.Printf("Opponent %s has been eliminated", p.Name)
+			continue
+		}
+
+		opponents[p.Name] = player
+	}
+
+	return opponents, nil
+}
+
+// poolStreaks finds the best streaks for each player, then rescores them by the probability of being the last survivor in the pool.
+// Opponents are assumed to follow the best streak found for them under the survival objective.
+// Every candidate is simulated against the same sampled seasons so that their win probabilities can be compared fairly.
+func poolStreaks(ctx context.Context, players bts.PlayerMap, opponents bts.PlayerMap, schedule *bts.Schedule, model bts.PredictionModel, predictions *bts.Predictions, obj bts.Objective, iterations int) (<-chan streakMap, error) {
+	log.Printf("Planning streaks for %d opponents", len(opponents))
+	plans := <-calculateBestStreaks(perPlayerStreaks(playerIterator(opponents), predictions, bts.MaxSurvival{}))
+	pool := make(map[string]*bts.Streak)
+	for name, top := range plans {
+		if top.Len() == 0 {
+			log.Printf("Opponent %s has no possible streak", name)
+			continue
+		}
+		pool[name] = top.Results()[0].Streak
+		log.Printf("Opponent %s plans streak %s", name, pool[name])
+	}
+
+	candidates := <-calculateBestStreaks(perPlayerStreaks(playerIterator(players), predictions, obj))
+
+	sd := *seed
+	if sd < 0 {
+		sd = time.Now().UnixNano()
+	}
+
+	sm := make(streakMap)
+	for name, top := range candidates {
+		rescored := bts.NewTopStreaks(*topK)
+		for _, r := range top.Results() {
+			pool[name] = r.Streak
+			wins, err := bts.SimulatePool(ctx, rand.New(rand.NewSource(sd)), schedule, model, pool, iterations)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("Player %s: p(win pool)=%f, streak=%s", name, wins[name], r.Streak)
+			r.Score = bts.Score{Value: wins[name], Tiebreak: r.Probability}
+			rescored.Push(r)
+		}
+		delete(pool, name)
+		sm[name] = rescored
+	}
+
+	out := make(chan streakMap, 1)
+	out <- sm
+	close(out)
+	return out, nil
+}
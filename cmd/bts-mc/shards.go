@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+)
+
+// ShardPrediction holds the best streaks found by an exhaustive search over one shard of a picker's possible streaks.
+// Shards with the same job are merged by a reduce request.
+type ShardPrediction struct {
+	Job    string                 `firestore:"job"`
+	Shard  int                    `firestore:"shard"`
+	Shards int                    `firestore:"shards"`
+	Picker *firestore.DocumentRef `firestore:"picker"`
+	Week   int                    `firestore:"week"`
+
+	PossiblePicks []StreakPrediction `firestore:"possible_picks"`
+
+	// CalculationStartTime is when the program that produced the results started
+	CalculationStartTime time.Time `firestore:"calculation_start_time"`
+	// CalculationEndTime is when the results were generated and finalized
+	CalculationEndTime time.Time `firestore:"calculation_end_time"`
+}
+
+// exhaustiveStreaks searches every possible streak of every player, splitting each search into the given number of concurrent shards.
+func exhaustiveStreaks(ctx context.Context, players bts.PlayerMap, predictions *bts.Predictions, obj bts.Objective, shards int, k int) (<-chan streakMap, error) {
+	sm := make(streakMap)
+	for _, p := range players {
+		log.Printf("Player %s: searching %v streaks in %d shards", p.Name(), p.NumberOfStreaks(), shards)
+		top, err := bts.SearchShards(ctx, p, predictions, obj, shards, k)
+		if err != nil {
+			return nil, err
+		}
+		sm.merge(p.Name(), top, k)
+	}
+
+	out := make(chan streakMap, 1)
+	out <- sm
+	close(out)
+	return out, nil
+}
+
+// searchShard searches one shard of a single player's possible streaks and writes the best results to Firestore for a later reduce request.
+func searchShard(ctx context.Context, fs *firestore.Client, rm RequestMessage, pickerRef *firestore.DocumentRef, p *bts.Player, predictions *bts.Predictions, obj bts.Objective, weekNumber int, k int) error {
+	startTime := time.Now()
+
+	ranges := bts.ShardRanks(p.NumberOfStreaks(), rm.Shards)
+	r := ranges[*rm.Shard]
+	log.Printf("Player %s: searching shard %d of %d, ranks %s", p.Name(), *rm.Shard, rm.Shards, r)
+
+	top, err := bts.SearchRange(ctx, p, predictions, obj, r, k)
+	if err != nil {
+		return err
+	}
+
+	picks := make([]StreakPrediction, 0, top.Len())
+	for _, res := range top.Results() {
+		picks = append(picks, makeStreakPrediction(res, predictions, weekNumber))
+	}
+
+	sp := ShardPrediction{
+		Job:                  rm.Job,
+		Shard:                *rm.Shard,
+		Shards:               rm.Shards,
+		Picker:               pickerRef,
+		Week:                 weekNumber,
+		PossiblePicks:        picks,
+		CalculationStartTime: startTime,
+		CalculationEndTime:   time.Now(),
+	}
+	_, wr, err := fs.Collection("streak_prediction_shards").Add(ctx, sp)
+	if err != nil {
+		return err
+	}
+	log.Printf("Wrote shard %v", wr)
+	return nil
+}
+
+// reduceShards merges the best streaks of every shard of a job into a single prediction.
+// Every shard of the job must have been written before the job can be reduced.
+func reduceShards(ctx context.Context, fs *firestore.Client, job string, pickerName string, pickerRef *firestore.DocumentRef, weekNumber int, k int) (map[string]PickerPrediction, error) {
+	startTime := time.Now()
+
+	docs, err := fs.Collection("streak_prediction_shards").Where("job", "==", job).Where("picker", "==", pickerRef).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no shards found for job %s", job)
+	}
+
+	found := make(map[int]bool)
+	nShards := -1
+	picks := make([]StreakPrediction, 0)
+	for _, doc := range docs {
+		var sp ShardPrediction
+		if err := doc.DataTo(&sp); err != nil {
+			return nil, err
+		}
+		if nShards >= 0 && sp.Shards != nShards {
+			return nil, fmt.Errorf("job %s has inconsistent shard counts %d and %d", job, nShards, sp.Shards)
+		}
+		if sp.Week != weekNumber {
+			return nil, fmt.Errorf("job %s shard %d is for week %d, not week %d", job, sp.Shard, sp.Week, weekNumber)
+		}
+		nShards = sp.Shards
+		found[sp.Shard] = true
+		picks = append(picks, sp.PossiblePicks...)
+	}
+	for i := 0; i < nShards; i++ {
+		if !found[i] {
+			return nil, fmt.Errorf("job %s is missing shard %d of %d", job, i, nShards)
+		}
+	}
+	log.Printf("Reducing %d shards of job %s", nShards, job)
+
+	out := make(map[string]PickerPrediction)
+	if len(picks) == 0 {
+		return out, nil
+	}
+	sort.Sort(ByScoreDesc(picks))
+	picks = distinctStreakPredictions(picks)
+	if len(picks) > k {
+		picks = picks[:k]
+	}
+	out[pickerName] = makePickerPrediction(picks, weekNumber, startTime)
+	return out, nil
+}
+
+// distinctStreakPredictions removes streak predictions that pick the same teams in the same weeks as a prediction earlier in the slice.
+// Predictions are expected to be in canonical order, as produced by makeStreakPrediction.
+func distinctStreakPredictions(sps []StreakPrediction) []StreakPrediction {
+	seen := make(map[string]bool)
+	out := make([]StreakPrediction, 0, len(sps))
+	for _, sp := range sps {
+		var b strings.Builder
+		for _, week := range sp.Weeks {
+			fmt.Fprintf(&b, "%d:", week.WeekNumber)
+			for _, pick := range week.Pick {
+				if pick != nil {
+					b.WriteString(pick.ID)
+				}
+				b.WriteRune(',')
+			}
+			b.WriteRune(';')
+		}
+		key := b.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, sp)
+	}
+	return out
+}
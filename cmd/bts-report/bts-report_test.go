@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestReport(t *testing.T) {
+	teamNames["a"], teamNames["b"], teamNames["c"] = "AAA", "BBB", "CCC"
+	ref := func(id string) *firestore.DocumentRef { return &firestore.DocumentRef{ID: id} }
+	calculated := time.Date(2020, time.September, 10, 12, 0, 0, 0, time.UTC)
+	weeks := []Week{
+		{WeekNumber: 3, Pick: []*firestore.DocumentRef{ref("a")}, Probabilities: []float64{.9}},
+		{WeekNumber: 4, Pick: []*firestore.DocumentRef{ref("b"), ref("c")}, Probabilities: []float64{.8, .5}},
+		{WeekNumber: 5},
+	}
+
+	tests := []struct {
+		name     string
+		pp       PickerPrediction
+		expected string
+	}{
+		{
+			name: "stored survival",
+			pp: PickerPrediction{
+				Week:               3,
+				Probability:        .36,
+				Spread:             12.5,
+				PossiblePicks:      []StreakPrediction{{Weeks: weeks}},
+				SurvivalCurve:      []float64{.9, .36, .36},
+				ExpectedWeeks:      1.62,
+				LargestDropWeek:    4,
+				LargestDrop:        .54,
+				Objective:          "weeks",
+				CalculationEndTime: calculated,
+			},
+			expected: `Picker Luke M, week 3 (objective weeks, calculated 2020-09-10T12:00:00Z)
+
+Week  Picks    P(win week)  P(survive)  Drop    
+3     AAA      0.9000       0.9000      0.1000  
+4     BBB CCC  0.4000       0.3600      0.5400  
+5     ----     1.0000       0.3600      0.0000  
+
+Probability of surviving every week: 0.3600 (total spread 12.5)
+Expected weeks survived: 1.62 of 3
+Largest drop in survival: 0.5400 in week 4
+`,
+		},
+		{
+			name: "survival calculated for predictions made before it was stored",
+			pp: PickerPrediction{
+				Week:               3,
+				Probability:        .36,
+				Spread:             12.5,
+				PossiblePicks:      []StreakPrediction{{Weeks: weeks}},
+				CalculationEndTime: calculated,
+			},
+			expected: `Picker Luke M, week 3 (objective survival, calculated 2020-09-10T12:00:00Z)
+
+Week  Picks    P(win week)  P(survive)  Drop    
+3     AAA      0.9000       0.9000      0.1000  
+4     BBB CCC  0.4000       0.3600      0.5400  
+5     ----     1.0000       0.3600      0.0000  
+
+Probability of surviving every week: 0.3600 (total spread 12.5)
+Expected weeks survived: 1.62 of 3
+Largest drop in survival: 0.5400 in week 4
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := report(&b, "Luke M", &test.pp); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != test.expected {
+				t.Errorf("expected\n%s\ngot\n%s", test.expected, b.String())
+			}
+		})
+	}
+}
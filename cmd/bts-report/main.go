@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"google.golang.org/api/iterator"
+)
+
+var fsclient *firestore.Client
+
+var projectID = os.Getenv("GCP_PROJECT")
+
+var pickerFlag = flag.String("picker", "", "Picker to report (required).")
+var weekFlag = flag.Int("week", -1, "Week of the prediction to report (starting at 0 for preseason). Negative values report the most recent prediction.")
+
+// teamNames is a mapping of team document IDs to team names.
+var teamNames = make(map[string]string)
+
+// Week is a week's worth of picks.
+// TODO: Combine with bts-mc
+type Week struct {
+	WeekNumber    int                      `firestore:"week"`
+	Pick          []*firestore.DocumentRef `firestore:"pick"`
+	Probabilities []float64                `firestore:"probabilities"`
+	Spreads       []float64                `firestore:"spreads"`
+}
+
+// StreakPrediction is a prediction for a complete streak.
+// TODO: Combine with bts-mc
+type StreakPrediction struct {
+	CumulativeProbability float64 `firestore:"cumulative_probability"`
+	CumulativeSpread      float64 `firestore:"cumulative_spread"`
+	Weeks                 []Week  `firestore:"weeks"`
+}
+
+// PickerPrediction is the best streak of a picker, as stored in the streak_predictions collection.
+// Only the fields needed for the report are included.
+// TODO: Combine with bts-mc
+type PickerPrediction struct {
+	Picker *firestore.DocumentRef `firestore:"picker"`
+	Week   int                    `firestore:"week"`
+
+	Probability float64 `firestore:"probability"`
+	Spread      float64 `firestore:"spread"`
+
+	PossiblePicks []StreakPrediction `firestore:"possible_picks"`
+
+	SurvivalCurve   []float64 `firestore:"survival_curve"`
+	ExpectedWeeks   float64   `firestore:"expected_weeks"`
+	LargestDropWeek int       `firestore:"largest_drop_week"`
+	LargestDrop     float64   `firestore:"largest_drop"`
+
+	Objective string `firestore:"objective"`
+
+	CalculationEndTime time.Time `firestore:"calculation_end_time"`
+}
+
+// loadTeams loads the team name map, defined above.
+func loadTeams(ctx context.Context) error {
+	teamsItr := fsclient.Collection("teams").Documents(ctx)
+	defer teamsItr.Stop()
+	for {
+		teamDoc, err := teamsItr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name, err := teamDoc.DataAt("name_4")
+		if err != nil {
+			return err
+		}
+		teamNames[teamDoc.Ref.ID] = name.(string)
+	}
+	return nil
+}
+
+// latestPrediction gets the most recent prediction for a picker, optionally limited to a given week.
+func latestPrediction(ctx context.Context, picker string, week int) (*PickerPrediction, error) {
+	pickerDoc, err := fsclient.Collection("pickers").Where("name_luke", "==", picker).Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		pickerDoc, err = fsclient.Collection("pickers").Where("aliases", "array-contains", picker).Limit(1).Documents(ctx).Next()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("picker %s: %v", picker, err)
+	}
+
+	q := fsclient.Collection("streak_predictions").Where("picker", "==", pickerDoc.Ref)
+	if week >= 0 {
+		q = q.Where("week", "==", week)
+	}
+	predictionDoc, err := q.OrderBy("calculation_end_time", firestore.Desc).Limit(1).Documents(ctx).Next()
+	if err != nil {
+		return nil, fmt.Errorf("no streak prediction for picker %s: %v", picker, err)
+	}
+	log.Printf("latest streak prediction: %s", predictionDoc.Ref.ID)
+
+	var pp PickerPrediction
+	if err := predictionDoc.DataTo(&pp); err != nil {
+		return nil, err
+	}
+	if len(pp.PossiblePicks) == 0 {
+		return nil, fmt.Errorf("streak prediction %s has no possible picks", predictionDoc.Ref.ID)
+	}
+	return &pp, nil
+}
+
+// fillSurvival calculates the survival summary of the best streak for predictions made before it was stored.
+// Weeks before the week of the prediction are ignored, and weeks without picks are survived with certainty.
+func (pp *PickerPrediction) fillSurvival() {
+	if len(pp.SurvivalCurve) > 0 {
+		return
+	}
+	best := pp.PossiblePicks[0]
+	weeks := make([]bts.PickProbabilities, len(best.Weeks))
+	for i, week := range best.Weeks {
+		weeks[i] = bts.PickProbabilities{Week: week.WeekNumber, Probabilities: week.Probabilities}
+	}
+
+	summary := bts.SummarizeWeeks(bts.PickedWeekProbabilities(weeks, pp.Week))
+	pp.SurvivalCurve = summary.Curve
+	pp.ExpectedWeeks = summary.ExpectedWeeks
+	pp.LargestDropWeek = summary.LargestDropWeek + pp.Week
+	pp.LargestDrop = summary.LargestDrop
+}
+
+// report writes the survival curve of the best streak and its summary.
+func report(w io.Writer, picker string, pp *PickerPrediction) error {
+	pp.fillSurvival()
+	best := pp.PossiblePicks[0]
+
+	objective := pp.Objective
+	if objective == "" {
+		objective = "survival"
+	}
+	fmt.Fprintf(w, "Picker %s, week %d (objective %s, calculated %s)\n\n", picker, pp.Week, objective, pp.CalculationEndTime.Format(time.RFC3339))
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Week\tPicks\tP(win week)\tP(survive)\tDrop\t")
+	prev := 1.
+	for _, week := range best.Weeks {
+		names := make([]string, len(week.Pick))
+		pWeek := 1.
+		for j, ref := range week.Pick {
+			names[j] = teamNames[ref.ID]
+			pWeek *= week.Probabilities[j]
+		}
+		if len(names) == 0 {
+			names = append(names, "----")
+		}
+		// The survival curve starts with the week of the prediction.
+		cp := prev
+		if i := week.WeekNumber - pp.Week; i >= 0 && i < len(pp.SurvivalCurve) {
+			cp = pp.SurvivalCurve[i]
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%.4f\t%.4f\t%.4f\t\n", week.WeekNumber, strings.Join(names, " "), pWeek, cp, prev-cp)
+		prev = cp
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nProbability of surviving every week: %.4f (total spread %.1f)\n", pp.Probability, pp.Spread)
+	fmt.Fprintf(w, "Expected weeks survived: %.2f of %d\n", pp.ExpectedWeeks, len(best.Weeks))
+	fmt.Fprintf(w, "Largest drop in survival: %.4f in week %d\n", pp.LargestDrop, pp.LargestDropWeek)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if *pickerFlag == "" {
+		log.Fatalln("picker is required")
+	}
+
+	ctx := context.Background()
+
+	var err error
+	fsclient, err = firestore.NewClient(ctx, projectID)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer fsclient.Close()
+
+	if err := loadTeams(ctx); err != nil {
+		log.Fatalln(err)
+	}
+
+	pp, err := latestPrediction(ctx, *pickerFlag, *weekFlag)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := report(os.Stdout, *pickerFlag, pp); err != nil {
+		log.Fatalln(err)
+	}
+}
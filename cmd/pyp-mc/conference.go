@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"gopkg.in/yaml.v2"
+)
+
+// ConferenceYaml describes a conference in a conferences YAML file.
+// Teams are named as in the schedule. A conference lists either its divisions or its teams.
+type ConferenceYaml struct {
+	// Championship is whether the conference plays a championship game.
+	// With divisions, the division winners play; without, the top two teams play.
+	Championship bool                `yaml:"championship"`
+	Divisions    map[string][]string `yaml:"divisions"`
+	Teams        []string            `yaml:"teams"`
+}
+
+// ConferencesYaml is the format of the conferences YAML file.
+type ConferencesYaml map[string]ConferenceYaml
+
+// division is a group of teams ranked against one another.
+type division struct {
+	conference string
+	name       string
+	teams      []bts.Team
+	// members index the contenders of the simulator in the same order as teams.
+	members []int
+}
+
+// conference is a set of divisions whose members play conference games against one another.
+type conference struct {
+	name         string
+	divisions    []division
+	championship bool
+	// games index the simulated games played between members of the conference.
+	games []int
+}
+
+// loadConferences parses a YAML file of conferences, resolving team names to teams with teamsByName.
+func loadConferences(fileName string, teamsByName map[string]bts.Team) ([]conference, error) {
+	yf, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var cy ConferencesYaml
+	if err := yaml.Unmarshal(yf, &cy); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(cy))
+	for name := range cy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[bts.Team]string)
+	confs := make([]conference, 0, len(cy))
+	for _, name := range names {
+		c := cy[name]
+		if len(c.Divisions) > 0 && len(c.Teams) > 0 {
+			return nil, fmt.Errorf("conference %s lists both divisions and teams", name)
+		}
+		divisionTeams := c.Divisions
+		if len(divisionTeams) == 0 {
+			divisionTeams = map[string][]string{"": c.Teams}
+		}
+
+		divNames := make([]string, 0, len(divisionTeams))
+		for divName := range divisionTeams {
+			divNames = append(divNames, divName)
+		}
+		sort.Strings(divNames)
+
+		conf := conference{name: name, championship: c.Championship}
+		for _, divName := range divNames {
+			div := division{conference: name, name: divName}
+			for _, teamName := range divisionTeams[divName] {
+				team, ok := teamsByName[teamName]
+				if !ok {
+					return nil, fmt.Errorf("conference %s: team \"%s\" not in schedule", name, teamName)
+				}
+				if other, dup := seen[team]; dup {
+					return nil, fmt.Errorf("conference %s: team \"%s\" already in conference %s", name, teamName, other)
+				}
+				seen[team] = name
+				div.teams = append(div.teams, team)
+			}
+			conf.divisions = append(conf.divisions, div)
+		}
+		confs = append(confs, conf)
+	}
+	return confs, nil
+}
+
+// setConferences resolves the members of the conferences to contenders and finds the games played between them.
+// Only games that appear in the schedules of both teams count as conference games.
+func (ss *seasonSimulator) setConferences(confs []conference) error {
+	contender := make(map[bts.Team]int)
+	for i, t := range ss.teams {
+		contender[t] = i
+	}
+
+	memberOf := make(map[int]int)
+	for ci := range confs {
+		for di := range confs[ci].divisions {
+			div := &confs[ci].divisions[di]
+			div.members = make([]int, len(div.teams))
+			for i, t := range div.teams {
+				c, ok := contender[t]
+				if !ok {
+					return fmt.Errorf("conference %s: team %s not in schedule", confs[ci].name, t)
+				}
+				div.members[i] = c
+				memberOf[c] = ci
+			}
+		}
+	}
+
+	for gi, g := range ss.games {
+		if g.credit1 < 0 || g.credit2 < 0 {
+			continue
+		}
+		c1, ok1 := memberOf[g.credit1]
+		c2, ok2 := memberOf[g.credit2]
+		if ok1 && ok2 && c1 == c2 {
+			confs[c1].games = append(confs[c1].games, gi)
+		}
+	}
+
+	ss.conferences = confs
+	return nil
+}
+
+// standingsTally counts how often each contender finishes in each place of its division and reaches its conference championship game.
+type standingsTally struct {
+	places       [][]int
+	championship []int
+
+	// scratch space reused between seasons
+	confWins, confGames, h2h []int
+	tiebreak                 []float64
+	inGroup                  []bool
+}
+
+func (ss *seasonSimulator) newStandingsTally() *standingsTally {
+	n := len(ss.teams)
+	st := &standingsTally{
+		places:       make([][]int, n),
+		championship: make([]int, n),
+		confWins:     make([]int, n),
+		confGames:    make([]int, n),
+		h2h:          make([]int, n),
+		tiebreak:     make([]float64, n),
+		inGroup:      make([]bool, n),
+	}
+	for _, conf := range ss.conferences {
+		for _, div := range conf.divisions {
+			for _, c := range div.members {
+				st.places[c] = make([]int, len(div.members))
+			}
+		}
+	}
+	return st
+}
+
+// tallyStandings ranks every division given the outcome of every game in a season and counts the places.
+// won reports whether team1 won each game, and wins is the total number of wins of each contender.
+func (ss *seasonSimulator) tallyStandings(rng *rand.Rand, st *standingsTally, won []bool, wins []int) {
+	for _, conf := range ss.conferences {
+		for _, div := range conf.divisions {
+			for _, c := range div.members {
+				st.confWins[c] = 0
+				st.confGames[c] = 0
+			}
+		}
+		for _, gi := range conf.games {
+			g := ss.games[gi]
+			st.confGames[g.credit1]++
+			st.confGames[g.credit2]++
+			if won[gi] {
+				st.confWins[g.credit1]++
+			} else {
+				st.confWins[g.credit2]++
+			}
+		}
+
+		divisionWinners := make([]int, 0, len(conf.divisions))
+		for _, div := range conf.divisions {
+			order := ss.rankDivision(rng, st, conf, div, won, wins)
+			for place, c := range order {
+				st.places[c][place]++
+			}
+			if len(order) > 0 {
+				divisionWinners = append(divisionWinners, order[0])
+			}
+			if conf.championship && len(conf.divisions) == 1 && len(order) > 1 {
+				st.championship[order[0]]++
+				st.championship[order[1]]++
+			}
+		}
+		if conf.championship && len(conf.divisions) > 1 {
+			for _, c := range divisionWinners {
+				st.championship[c]++
+			}
+		}
+	}
+}
+
+// rankDivision orders the members of a division from first to last place.
+// Teams are ranked by conference winning percentage. Ties are broken by wins in games between the tied teams (head-to-head),
+// then by total wins, then at random.
+func (ss *seasonSimulator) rankDivision(rng *rand.Rand, st *standingsTally, conf conference, div division, won []bool, wins []int) []int {
+	order := make([]int, len(div.members))
+	copy(order, div.members)
+
+	pct := func(c int) float64 {
+		if st.confGames[c] == 0 {
+			return 0
+		}
+		return float64(st.confWins[c]) / float64(st.confGames[c])
+	}
+	sort.SliceStable(order, func(i, j int) bool { return pct(order[i]) > pct(order[j]) })
+
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && pct(order[end]) == pct(order[start]) {
+			end++
+		}
+		if end-start > 1 {
+			ss.breakTies(rng, st, conf, order[start:end], won, wins)
+		}
+		start = end
+	}
+	return order
+}
+
+// breakTies orders teams tied in conference winning percentage.
+func (ss *seasonSimulator) breakTies(rng *rand.Rand, st *standingsTally, conf conference, tied []int, won []bool, wins []int) {
+	for _, c := range tied {
+		st.inGroup[c] = true
+		st.h2h[c] = 0
+		st.tiebreak[c] = rng.Float64()
+	}
+	for _, gi := range conf.games {
+		g := ss.games[gi]
+		if !st.inGroup[g.credit1] || !st.inGroup[g.credit2] {
+			continue
+		}
+		if won[gi] {
+			st.h2h[g.credit1]++
+		} else {
+			st.h2h[g.credit2]++
+		}
+	}
+	sort.Slice(tied, func(i, j int) bool {
+		a, b := tied[i], tied[j]
+		switch {
+		case st.h2h[a] != st.h2h[b]:
+			return st.h2h[a] > st.h2h[b]
+		case wins[a] != wins[b]:
+			return wins[a] > wins[b]
+		default:
+			return st.tiebreak[a] < st.tiebreak[b]
+		}
+	})
+	for _, c := range tied {
+		st.inGroup[c] = false
+	}
+}
+
+// merge adds the counts of another tally.
+func (st *standingsTally) merge(o *standingsTally) {
+	for c := range st.places {
+		for place, count := range o.places[c] {
+			st.places[c][place] += count
+		}
+		st.championship[c] += o.championship[c]
+	}
+}
+
+// divisionResults is the probability of each team in a division finishing in each place and reaching the conference championship game.
+type divisionResults struct {
+	Conference   string
+	Division     string
+	Teams        []bts.Team
+	Places       [][]float64
+	Championship []float64
+}
+
+// standingsResults normalizes the tally of n seasons into results for each division.
+func (ss *seasonSimulator) standingsResults(st *standingsTally, n int) []divisionResults {
+	out := make([]divisionResults, 0)
+	for _, conf := range ss.conferences {
+		for _, div := range conf.divisions {
+			dr := divisionResults{
+				Conference:   conf.name,
+				Division:     div.name,
+				Teams:        div.teams,
+				Places:       make([][]float64, len(div.members)),
+				Championship: make([]float64, len(div.members)),
+			}
+			for i, c := range div.members {
+				dr.Places[i] = make([]float64, len(div.members))
+				for place, count := range st.places[c] {
+					dr.Places[i][place] = float64(count) / float64(n)
+				}
+				if conf.championship {
+					dr.Championship[i] = float64(st.championship[c]) / float64(n)
+				}
+			}
+			out = append(out, dr)
+		}
+	}
+	return out
+}
+
+// printStandings writes a table of place probabilities for each division.
+func printStandings(w io.Writer, results []divisionResults) {
+	for _, dr := range results {
+		title := dr.Conference
+		if dr.Division != "" {
+			title += " " + dr.Division
+		}
+		fmt.Fprintln(w, title)
+		fmt.Fprintf(w, " %15s ", "Team")
+		for place := range dr.Teams {
+			fmt.Fprintf(w, " %5d ", place+1)
+		}
+		fmt.Fprintf(w, " %5s \n", "CCG")
+		for i, t := range dr.Teams {
+			fmt.Fprintf(w, " %15s ", t.Name())
+			for _, p := range dr.Places[i] {
+				fmt.Fprintf(w, " %5.3f ", p)
+			}
+			fmt.Fprintf(w, " %5.3f \n", dr.Championship[i])
+		}
+		fmt.Fprintln(w)
+	}
+}
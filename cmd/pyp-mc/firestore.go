@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"google.golang.org/api/iterator"
+)
+
+// ModelPerformance holds Firestore data for model performance, parsed from ThePredictionTracker.com
+// TODO: Combine with bts-mc
+type ModelPerformance struct {
+	HomeBias          float64                `firestore:"bias"`
+	StandardDeviation float64                `firestore:"std_dev"`
+	Model             *firestore.DocumentRef `firestore:"model"`
+}
+
+// SagarinRating is a rating.  From Sagarin.  Stored in Firestore.  Simple.
+// TODO: Combine with bts-mc
+type SagarinRating struct {
+	Rating float64                `firestore:"rating"`
+	Team   *firestore.DocumentRef `firestore:"team"`
+}
+
+// loadFirestore builds the schedule and model from the latest ratings and model performance stored in Firestore.
+// Teams in the schedule are looked up by their other names.
+func loadFirestore(ctx context.Context, yamlSchedule YamlSchedule) (*simulationInputs, error) {
+	conf := &firebase.Config{}
+	app, err := firebase.NewApp(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := app.Firestore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	err = makeTeamLookup(ctx, fs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get most recent season
+	// TODO: Combine with bts-mc
+	iter := fs.Collection("seasons").OrderBy("start", firestore.Desc).Limit(1).Documents(ctx)
+	seasonDoc, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	iter.Stop()
+	log.Printf("latest season discovered: %s", seasonDoc.Ref.ID)
+
+	// Get most recent Sagarin Ratings proper
+	// TODO: Combine with bts-mc
+	iter = fs.Collection("sagarin").OrderBy("timestamp", firestore.Desc).Limit(1).Documents(ctx)
+	sagRateDoc, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	iter.Stop()
+	log.Printf("latest sagarin ratings discovered: %s", sagRateDoc.Ref.ID)
+
+	// Get most recent predictions
+	// TODO: Combine with bts-mc
+	iter = fs.Collection("prediction_tracker").OrderBy("timestamp", firestore.Desc).Limit(1).Documents(ctx)
+	predictionDoc, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	iter.Stop()
+	log.Printf("latest prediction tracker discovered: %s", predictionDoc.Ref.ID)
+
+	// Get Sagarin Rating performance
+	// TODO: Combine with bts-mc
+	iter = predictionDoc.Ref.Collection("model_performance").Where("system", "==", "Sagarin Points").Limit(1).Documents(ctx)
+	sagDoc, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	iter.Stop()
+	log.Printf("sagarin model performances discovered: %s", sagDoc.Ref.ID)
+
+	var sagPerf ModelPerformance
+	err = sagDoc.DataTo(&sagPerf)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Sagarin Ratings performance: %v", sagPerf)
+
+	homeAdvantage, err := sagRateDoc.DataAt("home_advantage_rating")
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Sagarin home advantage: %f", homeAdvantage)
+
+	// Get teams while we are at it--this is more efficient than making multiple calls
+	teamRefs := make([]*firestore.DocumentRef, 0)
+	ratings := make([]float64, 0)
+
+	iter = sagRateDoc.Ref.Collection("ratings").Documents(ctx)
+	for {
+		teamRatingDoc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var sr SagarinRating
+		err = teamRatingDoc.DataTo(&sr)
+		if err != nil {
+			return nil, err
+		}
+
+		//log.Printf("Sagarin rating: %v", sr)
+		teamRefs = append(teamRefs, sr.Team)
+		ratings = append(ratings, sr.Rating)
+	}
+	iter.Stop()
+	log.Printf("team ratings filled")
+
+	teamDocs, err := fs.GetAll(ctx, teamRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make([]bts.Team, len(teamDocs))
+	for i, td := range teamDocs {
+		var team bts.Team
+		err := td.DataTo(&team)
+		if err != nil {
+			return nil, err
+		}
+
+		// log.Printf("team %v", team)
+		teams[i] = team
+	}
+
+	// Build the probability model
+	ratingsMap := make(map[bts.Team]float64)
+	for i, t := range teams {
+		ratingsMap[t] = ratings[i]
+	}
+	homeBias := sagPerf.HomeBias + homeAdvantage.(float64)
+	closeBias := homeBias / 2.
+	model := bts.NewGaussianSpreadModel(ratingsMap, sagPerf.StandardDeviation, homeBias, closeBias)
+	if *travelFlag {
+		model = bts.NewTravelSpreadModel(ratingsMap, sagPerf.StandardDeviation, homeBias)
+	}
+
+	log.Printf("Built model %v", model)
+
+	schedule := make(bts.Schedule)
+	teamsByName := make(map[string]bts.Team)
+	lookupTeam := func(ref *firestore.DocumentRef) (bts.Team, error) {
+		doc, err := ref.Get(ctx)
+		if err != nil {
+			return bts.Team{}, err
+		}
+		var team bts.Team
+		if err := doc.DataTo(&team); err != nil {
+			return bts.Team{}, err
+		}
+		return team, nil
+	}
+	for t, weeks := range yamlSchedule {
+		team1, exists := otherTeamLookup[t]
+		if !exists {
+			return nil, fmt.Errorf(`team "%s" not found in teams`, t)
+		}
+		team, err := lookupTeam(team1)
+		if err != nil {
+			return nil, err
+		}
+		teamsByName[t] = team
+
+		schedule[team] = make([][]*bts.Game, len(weeks))
+		for i, week := range weeks {
+			schedule[team][i] = make([]*bts.Game, 0, len(week))
+			for _, opp := range week {
+				loc, other := splitLocTeam(opp.Opponent)
+				team2, exists := otherTeamLookup[other]
+				if !exists {
+					return nil, fmt.Errorf(`team "%s" not found in teams`, other)
+				}
+				op, err := lookupTeam(team2)
+				if err != nil {
+					return nil, err
+				}
+				game := bts.NewGame(team, op, loc)
+				game.SetKickoff(opp.Kickoff)
+				game.SetVenue(opp.Venue)
+				game.SetStatus(opp.Status)
+				if len(opp.Travel) == 2 {
+					game.SetTravel(opp.Travel[0], opp.Travel[1])
+				}
+				schedule[team][i] = append(schedule[team][i], game)
+			}
+		}
+	}
+
+	log.Printf("Schedule built:\n%v", schedule)
+
+	return &simulationInputs{schedule: schedule, teamsByName: teamsByName, model: model, std: sagPerf.StandardDeviation}, nil
+}
+
+var teamRefLookup = make(map[string]*firestore.DocumentRef)
+var otherTeamLookup = make(map[string]*firestore.DocumentRef)
+
+// TODO: Combine with bts-mc
+func makeTeamLookup(ctx context.Context, fs *firestore.Client) error {
+	teamIter := fs.Collection("teams").Documents(ctx)
+	defer teamIter.Stop()
+	for {
+		teamDoc, err := teamIter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		team4, err := teamDoc.DataAt("name_4")
+		if err != nil {
+			return err
+		}
+
+		teamRefLookup[team4.(string)] = teamDoc.Ref
+
+		otherNames, err := teamDoc.DataAt("other_names")
+		if err != nil {
+			return err
+		}
+
+		for _, n := range otherNames.([]interface{}) {
+			otherTeamLookup[n.(string)] = teamDoc.Ref
+		}
+
+	}
+	return nil
+}
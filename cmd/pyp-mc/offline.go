@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"gopkg.in/yaml.v2"
+)
+
+// simulationInputs are the schedule and model the simulation is built from.
+type simulationInputs struct {
+	schedule bts.Schedule
+	// teamsByName maps the names used in the schedule file to teams.
+	teamsByName map[string]bts.Team
+	model       bts.PredictionModel
+	// std is the standard deviation of the actual spread around the predicted spread.
+	std float64
+}
+
+// RatingsYaml is the format of the offline ratings file: team ratings and model performance parameters.
+// Teams are named as in the schedule.
+type RatingsYaml struct {
+	// HomeAdvantage is the rating system's home advantage.
+	HomeAdvantage float64 `yaml:"home_advantage"`
+	// Bias is the model's bias in favor of the home team.
+	Bias float64 `yaml:"bias"`
+	// StdDev is the standard deviation of the actual spread around the predicted spread.
+	StdDev  float64            `yaml:"std_dev"`
+	Ratings map[string]float64 `yaml:"ratings"`
+}
+
+// loadOffline builds the schedule and model from a local ratings file rather than Firestore.
+// Teams are named exactly as in the schedule, and every team in the schedule must be rated.
+func loadOffline(ratingsFile string, yamlSchedule YamlSchedule) (*simulationInputs, error) {
+	rf, err := ioutil.ReadFile(ratingsFile)
+	if err != nil {
+		return nil, err
+	}
+	var ry RatingsYaml
+	if err := yaml.Unmarshal(rf, &ry); err != nil {
+		return nil, err
+	}
+	if ry.StdDev <= 0 {
+		return nil, fmt.Errorf("ratings file %s: std_dev must be positive, got %f", ratingsFile, ry.StdDev)
+	}
+
+	ratingsMap := make(map[bts.Team]float64)
+	rated := func(name string) (bts.Team, error) {
+		rating, ok := ry.Ratings[name]
+		if !ok {
+			return bts.Team{}, fmt.Errorf("team \"%s\" has no rating in %s", name, ratingsFile)
+		}
+		team := bts.Team{Name4: name}
+		ratingsMap[team] = rating
+		return team, nil
+	}
+
+	schedule := make(bts.Schedule)
+	teamsByName := make(map[string]bts.Team)
+	for t, weeks := range yamlSchedule {
+		team, err := rated(t)
+		if err != nil {
+			return nil, err
+		}
+		teamsByName[t] = team
+
+		schedule[team] = make([][]*bts.Game, len(weeks))
+		for i, week := range weeks {
+			schedule[team][i] = make([]*bts.Game, 0, len(week))
+			for _, opp := range week {
+				loc, other := splitLocTeam(opp.Opponent)
+				op, err := rated(other)
+				if err != nil {
+					return nil, err
+				}
+				game := bts.NewGame(team, op, loc)
+				game.SetKickoff(opp.Kickoff)
+				game.SetVenue(opp.Venue)
+				game.SetStatus(opp.Status)
+				if len(opp.Travel) == 2 {
+					game.SetTravel(opp.Travel[0], opp.Travel[1])
+				}
+				schedule[team][i] = append(schedule[team][i], game)
+			}
+		}
+	}
+
+	homeBias := ry.Bias + ry.HomeAdvantage
+	closeBias := homeBias / 2.
+	model := bts.NewGaussianSpreadModel(ratingsMap, ry.StdDev, homeBias, closeBias)
+	if *travelFlag {
+		model = bts.NewTravelSpreadModel(ratingsMap, ry.StdDev, homeBias)
+	}
+
+	return &simulationInputs{schedule: schedule, teamsByName: teamsByName, model: model, std: ry.StdDev}, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"gopkg.in/yaml.v2"
+)
+
+// OutcomesYaml is the format of the outcomes YAML file: the results of each team's games in each week, in the same order as the schedule.
+// 1 is a win, 0 is a loss, and null (~) marks a game that has not been played. Entries for bye weeks and for cancelled and no-contest games are ignored.
+// Weeks past the end of a team's list have not been played.
+type OutcomesYaml map[string][]WeekOutcomes
+
+// WeekOutcomes is the results of a team's games in one week, in the same order as the games in the schedule.
+// In YAML, weeks with one game are written as a single result, and weeks with more than one game as a list of results.
+// A null week has no results.
+type WeekOutcomes []*int
+
+// UnmarshalYAML parses either a single result or a list of results.
+func (wo *WeekOutcomes) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var r *int
+	if err := unmarshal(&r); err == nil {
+		*wo = WeekOutcomes{r}
+		return nil
+	}
+	var rs []*int
+	if err := unmarshal(&rs); err != nil {
+		return err
+	}
+	*wo = rs
+	return nil
+}
+
+// gameOutcome is the known result of a simulated game.
+type gameOutcome int8
+
+const (
+	unplayed gameOutcome = iota
+	team1Won
+	team2Won
+)
+
+// loadOutcomes parses a YAML file of game outcomes.
+func loadOutcomes(fileName string) (OutcomesYaml, error) {
+	yf, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var oy OutcomesYaml
+	if err := yaml.Unmarshal(yf, &oy); err != nil {
+		return nil, err
+	}
+	for team, weeks := range oy {
+		for week, results := range weeks {
+			for _, r := range results {
+				if r != nil && *r != 0 && *r != 1 {
+					return nil, fmt.Errorf("team %s week %d: outcome must be 0, 1, or null, got %d", team, week, *r)
+				}
+			}
+		}
+	}
+	return oy, nil
+}
+
+// setOutcomes fixes the results of games already played, so that only the remaining games are simulated.
+// Team names are resolved with teamsByName. Teams reporting different results for the same game are an error,
+// as are weeks with a different number of results than the team has games.
+func (ss *seasonSimulator) setOutcomes(oy OutcomesYaml, teamsByName map[string]bts.Team) error {
+	contender := make(map[bts.Team]int)
+	for i, t := range ss.teams {
+		contender[t] = i
+	}
+
+	type slot struct{ team, week, order int }
+	gameOf := make(map[slot]int)
+	for gi, g := range ss.games {
+		gameOf[slot{g.credit1, g.week, g.order1}] = gi
+		if g.credit2 >= 0 {
+			gameOf[slot{g.credit2, g.week, g.order2}] = gi
+		}
+	}
+
+	fixed := 0
+	for name, weeks := range oy {
+		team, ok := teamsByName[name]
+		if !ok {
+			return fmt.Errorf("outcome team \"%s\" not in schedule", name)
+		}
+		c, ok := contender[team]
+		if !ok {
+			return fmt.Errorf("outcome team \"%s\" is not a contender", name)
+		}
+		for week, results := range weeks {
+			if week >= len(ss.scheduled[c]) || ss.scheduled[c][week] == 0 {
+				// bye week
+				continue
+			}
+			n := ss.scheduled[c][week]
+			if len(results) > 0 && len(results) != n {
+				return fmt.Errorf("team %s week %d: %d outcomes given for %d games", name, week, len(results), n)
+			}
+			for order, r := range results {
+				if r == nil {
+					continue
+				}
+
+				gi, ok := gameOf[slot{c, week, order}]
+				if !ok {
+					// cancelled or no-contest
+					continue
+				}
+				g := &ss.games[gi]
+				won := *r == 1
+				outcome := team2Won
+				if won == (g.credit1 == c) {
+					outcome = team1Won
+				}
+				if g.outcome != unplayed && g.outcome != outcome {
+					return fmt.Errorf("team %s week %d: outcome conflicts with the opponent's outcome", name, week)
+				}
+				if g.outcome == unplayed {
+					fixed++
+				}
+				g.outcome = outcome
+			}
+		}
+	}
+
+	ss.fixed = fixed
+	return nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sortScores sorts the teams by name ("team"), by expected wins ("wins"), or by expected score ("score").
+// Wins and scores are sorted from highest to lowest, with ties broken by team name.
+func sortScores(scores []teamScore, by string) error {
+	var key func(s teamScore) float64
+	switch by {
+	case "team":
+	case "wins":
+		key = func(s teamScore) float64 { return s.ExpectedWins }
+	case "score":
+		key = func(s teamScore) float64 { return s.ExpectedScore }
+	default:
+		return fmt.Errorf("unable to sort by \"%s\": use team, wins, or score", by)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if key != nil && key(scores[i]) != key(scores[j]) {
+			return key(scores[i]) > key(scores[j])
+		}
+		return scores[i].Team.Name() < scores[j].Team.Name()
+	})
+	return nil
+}
+
+// writeOutput writes the results in the given format: table, csv, json, or markdown.
+// CSV output has one row per team and omits conference standings.
+func writeOutput(w io.Writer, format string, scores []teamScore, standings []divisionResults, recs []recommendation) error {
+	switch format {
+	case "table":
+		printResults(w, scores)
+		if len(standings) > 0 {
+			fmt.Fprintln(w)
+			printStandings(w, standings)
+		}
+		fmt.Fprintln(w)
+		printScores(w, scores, recs)
+		return nil
+	case "csv":
+		return exportCSV(w, scores, recs)
+	case "json":
+		return exportJSON(w, scores, standings, recs)
+	case "markdown":
+		printMarkdown(w, scores, standings, recs)
+		return nil
+	default:
+		return fmt.Errorf("unknown output format \"%s\": use table, csv, json, or markdown", format)
+	}
+}
+
+// formatForFile chooses the output format from the extension of a file name.
+func formatForFile(fileName string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(fileName)); ext {
+	case ".json":
+		return "json", nil
+	case ".csv":
+		return "csv", nil
+	case ".md":
+		return "markdown", nil
+	case ".txt":
+		return "table", nil
+	default:
+		return "", fmt.Errorf("unable to export to file with extension \"%s\": use .json, .csv, .md, or .txt", ext)
+	}
+}
+
+// exportResults writes the results to a file, formatted by the file's extension.
+func exportResults(fileName string, scores []teamScore, standings []divisionResults, recs []recommendation) error {
+	format, err := formatForFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := writeOutput(f, format, scores, standings, recs); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// printResults writes a table of the probability of winning exactly k games for each team, followed by a table of the probability of winning at least k games.
+// Teams that play fewer games than others have blank columns for the wins they cannot reach.
+func printResults(w io.Writer, results []teamScore) {
+	maxGames := 0
+	for _, r := range results {
+		if r.Games > maxGames {
+			maxGames = r.Games
+		}
+	}
+
+	printHeader := func(title string) {
+		fmt.Fprintf(w, "%s\n", title)
+		fmt.Fprintf(w, " %15s  %5s  %6s  %6s ", "Team", "Games", "E[W]", "Median")
+		for k := 0; k <= maxGames; k++ {
+			fmt.Fprintf(w, " %5d ", k)
+		}
+		fmt.Fprintln(w)
+	}
+	printRow := func(r teamScore, probs []float64) {
+		fmt.Fprintf(w, " %15s  %5d  %6.3f  %6d ", r.Team.Name(), r.Games, r.ExpectedWins, r.MedianWins)
+		for k := 0; k <= maxGames; k++ {
+			if k < len(probs) {
+				fmt.Fprintf(w, " %5.3f ", probs[k])
+			} else {
+				fmt.Fprintf(w, " %5s ", "")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	printHeader("P(wins = k)")
+	for _, r := range results {
+		printRow(r, r.WinProbabilities)
+	}
+	fmt.Fprintln(w)
+
+	printHeader("P(wins >= k)")
+	for _, r := range results {
+		printRow(r, r.AtLeast)
+	}
+}
+
+// exportTeam is the exported form of a team's results.
+type exportTeam struct {
+	Team             string    `json:"team"`
+	Games            int       `json:"games"`
+	ExpectedWins     float64   `json:"expected_wins"`
+	MedianWins       int       `json:"median_wins"`
+	ExpectedScore    float64   `json:"expected_score"`
+	ScoreVariance    float64   `json:"score_variance"`
+	WinProbabilities []float64 `json:"win_probabilities"`
+	AtLeast          []float64 `json:"at_least"`
+}
+
+// exportDivision is the exported form of a division's standings.
+type exportDivision struct {
+	Conference   string      `json:"conference"`
+	Division     string      `json:"division,omitempty"`
+	Teams        []string    `json:"teams"`
+	Places       [][]float64 `json:"places"`
+	Championship []float64   `json:"championship"`
+}
+
+func exportJSON(w io.Writer, scores []teamScore, standings []divisionResults, recs []recommendation) error {
+	out := struct {
+		Teams           []exportTeam     `json:"teams"`
+		Standings       []exportDivision `json:"standings,omitempty"`
+		Recommendations []recommendation `json:"recommendations"`
+	}{Teams: make([]exportTeam, len(scores)), Recommendations: recs}
+	for _, dr := range standings {
+		ed := exportDivision{Conference: dr.Conference, Division: dr.Division, Places: dr.Places, Championship: dr.Championship}
+		for _, t := range dr.Teams {
+			ed.Teams = append(ed.Teams, t.Name())
+		}
+		out.Standings = append(out.Standings, ed)
+	}
+	for i, s := range scores {
+		out.Teams[i] = exportTeam{
+			Team:             s.Team.Name(),
+			Games:            s.Games,
+			ExpectedWins:     s.ExpectedWins,
+			MedianWins:       s.MedianWins,
+			ExpectedScore:    s.ExpectedScore,
+			ScoreVariance:    s.ScoreVariance,
+			WinProbabilities: s.WinProbabilities,
+			AtLeast:          s.AtLeast,
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func exportCSV(w io.Writer, scores []teamScore, recs []recommendation) error {
+	recommendedFor := make(map[string][]string)
+	for _, r := range recs {
+		recommendedFor[r.Team] = append(recommendedFor[r.Team], r.Picker)
+	}
+
+	maxGames := 0
+	for _, s := range scores {
+		if s.Games > maxGames {
+			maxGames = s.Games
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{"team", "games", "expected_wins", "median_wins", "expected_score", "score_variance", "recommended_for"}
+	for k := 0; k <= maxGames; k++ {
+		header = append(header, fmt.Sprintf("p_%d", k))
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	f := func(x float64) string { return strconv.FormatFloat(x, 'f', -1, 64) }
+	for _, s := range scores {
+		row := []string{
+			s.Team.Name(),
+			strconv.Itoa(s.Games),
+			f(s.ExpectedWins),
+			strconv.Itoa(s.MedianWins),
+			f(s.ExpectedScore),
+			f(s.ScoreVariance),
+			strings.Join(recommendedFor[s.Team.Name()], ";"),
+		}
+		for k := 0; k <= maxGames; k++ {
+			if k < len(s.WinProbabilities) {
+				row = append(row, f(s.WinProbabilities[k]))
+			} else {
+				row = append(row, "")
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// printMarkdown writes the results as Markdown tables, suitable for pasting into an email.
+func printMarkdown(w io.Writer, scores []teamScore, standings []divisionResults, recs []recommendation) {
+	maxGames := 0
+	for _, s := range scores {
+		if s.Games > maxGames {
+			maxGames = s.Games
+		}
+	}
+
+	row := func(cells ...string) {
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+	rule := func(n int) {
+		cells := make([]string, n)
+		for i := range cells {
+			cells[i] = "---"
+		}
+		row(cells...)
+	}
+	f := func(x float64) string { return strconv.FormatFloat(x, 'f', 3, 64) }
+
+	fmt.Fprintln(w, "## Wins")
+	fmt.Fprintln(w)
+	header := []string{"Team", "Games", "E[W]", "Median", "E[Score]", "Var"}
+	for k := 0; k <= maxGames; k++ {
+		header = append(header, fmt.Sprintf("P(W≥%d)", k))
+	}
+	row(header...)
+	rule(len(header))
+	for _, s := range scores {
+		cells := []string{s.Team.Name(), strconv.Itoa(s.Games), f(s.ExpectedWins), strconv.Itoa(s.MedianWins), f(s.ExpectedScore), f(s.ScoreVariance)}
+		for k := 0; k <= maxGames; k++ {
+			if k < len(s.AtLeast) {
+				cells = append(cells, f(s.AtLeast[k]))
+			} else {
+				cells = append(cells, "")
+			}
+		}
+		row(cells...)
+	}
+
+	for _, dr := range standings {
+		title := dr.Conference
+		if dr.Division != "" {
+			title += " " + dr.Division
+		}
+		fmt.Fprintf(w, "\n## %s\n\n", title)
+		header := []string{"Team"}
+		for place := range dr.Teams {
+			header = append(header, strconv.Itoa(place+1))
+		}
+		header = append(header, "CCG")
+		row(header...)
+		rule(len(header))
+		for i, t := range dr.Teams {
+			cells := []string{t.Name()}
+			for _, p := range dr.Places[i] {
+				cells = append(cells, f(p))
+			}
+			cells = append(cells, f(dr.Championship[i]))
+			row(cells...)
+		}
+	}
+
+	if len(recs) > 0 {
+		fmt.Fprintf(w, "\n## Recommendations\n\n")
+		row("Picker", "Team", "E[Score]", "Var")
+		rule(4)
+		for _, r := range recs {
+			row(r.Picker, r.Team, f(r.ExpectedScore), f(r.ScoreVariance))
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"gopkg.in/yaml.v2"
+)
+
+func testSimulator() *seasonSimulator {
+	a := bts.Team{Name4: "A"}
+	b := bts.Team{Name4: "B"}
+	c := bts.Team{Name4: "C"}
+	s := bts.ScheduleFromNotation(map[string][]string{
+		"A": {"B", "@C", ""},
+		"B": {"@A", "", "A"},
+	})
+	ratings := map[bts.Team]float64{a: 10, b: 5, c: 0}
+	model := bts.NewGaussianSpreadModel(ratings, 14, 3, 1.5)
+	return newSeasonSimulator(s, model, 14, 4.7)
+}
+
+func TestSeasonSimulatorGames(t *testing.T) {
+	ss := testSimulator()
+	// A-B in week 0 is shared, A@C in week 1 and A@B in week 2 (only on B's schedule) are not.
+	if len(ss.games) != 3 {
+		t.Fatalf("expected 3 distinct games, got %d", len(ss.games))
+	}
+	if ss.games[0].credit1 < 0 || ss.games[0].credit2 < 0 {
+		t.Errorf("expected shared game to credit both contenders, got %+v", ss.games[0])
+	}
+}
+
+func TestSeasonSimulatorRun(t *testing.T) {
+	ss := testSimulator()
+	r1, _ := ss.run(10000, 4, 42)
+	r2, _ := ss.run(10000, 4, 42)
+	if !reflect.DeepEqual(r1, r2) {
+		t.Errorf("expected identical results with the same seed")
+	}
+	if r3, _ := ss.run(10000, 3, 42); !reflect.DeepEqual(r1, r3) {
+		t.Errorf("expected identical results with a different number of workers")
+	}
+
+	for _, r := range r1 {
+		sum := 0.
+		for _, p := range r.WinProbabilities {
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("team %s: expected win probabilities to sum to 1, got %f", r.Team, sum)
+		}
+	}
+
+	// Byes are not games, so A and B each play two.
+	for _, r := range r1 {
+		if r.Games != 2 || len(r.WinProbabilities) != 3 {
+			t.Errorf("team %s: expected 2 games, got %d with probabilities %v", r.Team, r.Games, r.WinProbabilities)
+		}
+	}
+}
+
+func TestSeasonSimulatorRunTiebreaks(t *testing.T) {
+	a := bts.Team{Name4: "A"}
+	b := bts.Team{Name4: "B"}
+	s := bts.ScheduleFromNotation(map[string][]string{
+		"A": {"B", "@B"},
+		"B": {"@A", "A"},
+	})
+	model := bts.NewGaussianSpreadModel(map[bts.Team]float64{a: 0, b: 0}, 14, 3, 1.5)
+	ss := newSeasonSimulator(s, model, 14, 4.7)
+	r1, _ := ss.run(10000, 4, 42)
+
+	// A and B split their games often, so their standings need tiebreaks, which must not change the games played.
+	confs := []conference{{
+		name:      "Conf",
+		divisions: []division{{conference: "Conf", name: "Conf", teams: []bts.Team{a, b}}},
+	}}
+	if err := ss.setConferences(confs); err != nil {
+		t.Fatal(err)
+	}
+	if r2, _ := ss.run(10000, 4, 42); !reflect.DeepEqual(r1, r2) {
+		t.Errorf("expected identical results when tallying conference standings")
+	}
+}
+
+func TestMakeTeamResults(t *testing.T) {
+	r := makeTeamResults(bts.Team{Name4: "A"}, []float64{.1, .2, .3, .4})
+	if r.Games != 3 {
+		t.Errorf("expected 3 games, got %d", r.Games)
+	}
+	if math.Abs(r.ExpectedWins-2) > 1e-12 {
+		t.Errorf("expected 2 wins, got %f", r.ExpectedWins)
+	}
+	if r.MedianWins != 2 {
+		t.Errorf("expected median of 2 wins, got %d", r.MedianWins)
+	}
+	atLeast := []float64{1, .9, .7, .4}
+	for k, p := range atLeast {
+		if math.Abs(r.AtLeast[k]-p) > 1e-12 {
+			t.Errorf("expected P(wins >= %d) = %f, got %f", k, p, r.AtLeast[k])
+		}
+	}
+}
+
+func TestScoringRules(t *testing.T) {
+	sr := ScoringRules{
+		PointsPerWin: 1,
+		Bonuses:      []Bonus{{Wins: 2, Points: 2}},
+		Pickers:      map[string][]string{"Phil K": {"long name for A"}, "Luke M": nil},
+	}
+	if got := sr.score(2); got != 4 {
+		t.Errorf("expected 4 points for 2 wins, got %f", got)
+	}
+
+	a := makeTeamResults(bts.Team{Name4: "A"}, []float64{.5, 0, .5})
+	b := makeTeamResults(bts.Team{Name4: "B"}, []float64{0, 1, 0})
+	scores := sr.scoreTeams([]teamResults{a, b})
+	// A scores 0 or 4 with equal probability, B always scores 1.
+	if math.Abs(scores[0].ExpectedScore-2) > 1e-12 || math.Abs(scores[0].ScoreVariance-4) > 1e-12 {
+		t.Errorf("expected A to score 2 with variance 4, got %f with variance %f", scores[0].ExpectedScore, scores[0].ScoreVariance)
+	}
+	if math.Abs(scores[1].ExpectedScore-1) > 1e-12 || math.Abs(scores[1].ScoreVariance) > 1e-12 {
+		t.Errorf("expected B to score 1 with variance 0, got %f with variance %f", scores[1].ExpectedScore, scores[1].ScoreVariance)
+	}
+
+	recs, err := sr.recommend(scores, map[string]bts.Team{"long name for A": {Name4: "A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []recommendation{
+		{Picker: "Luke M", Team: "A", ExpectedScore: 2, ScoreVariance: 4},
+		{Picker: "Phil K", Team: "A", ExpectedScore: 2, ScoreVariance: 4},
+	}
+	if !reflect.DeepEqual(recs, want) {
+		t.Errorf("expected recommendations %v, got %v", want, recs)
+	}
+
+	sr.Pickers["Bad"] = []string{"Z"}
+	_, err = sr.recommend(scores, map[string]bts.Team{"long name for A": {Name4: "A"}})
+	if err == nil || !strings.Contains(err.Error(), "Bad") || !strings.Contains(err.Error(), "Z") {
+		t.Errorf("expected error naming picker Bad and team Z, got %v", err)
+	}
+
+	sr.Pickers["Bad"] = []string{"C"}
+	if _, err := sr.recommend(scores, map[string]bts.Team{"long name for A": {Name4: "A"}, "C": {Name4: "C"}}); err == nil {
+		t.Errorf("expected error for picker with no teams in the schedule")
+	}
+}
+
+func TestLoadScoringRules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		f, err := ioutil.TempFile(dir, "scoring*.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(f, content)
+		f.Close()
+		return f.Name()
+	}
+
+	sr, err := loadScoringRules(write("bonuses: [{wins: 10, points: 5}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Points per win are not given, so they keep their default.
+	if sr.PointsPerWin != defaultScoringRules.PointsPerWin || len(sr.Bonuses) != 1 {
+		t.Errorf("expected default points per win with one bonus, got %+v", sr)
+	}
+
+	sr, err = loadScoringRules(write("points_per_win: 0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr.PointsPerWin != 0 {
+		t.Errorf("expected 0 points per win, got %f", sr.PointsPerWin)
+	}
+
+	if _, err := loadScoringRules(write("bonuses: [{wins: -1, points: 5}]")); err == nil {
+		t.Errorf("expected error for bonus with negative wins")
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	scores := defaultScoringRules.scoreTeams([]teamResults{makeTeamResults(bts.Team{Name4: "A"}, []float64{.25, .75})})
+	var b strings.Builder
+	if err := exportCSV(&b, scores, []recommendation{{Picker: "Phil K", Team: "A"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "team,games,expected_wins,median_wins,expected_score,score_variance,recommended_for,p_0,p_1\nA,1,0.75,1,0.75,0.1875,Phil K,0.25,0.75\n"
+	if b.String() != want {
+		t.Errorf("expected CSV\n%s\ngot\n%s", want, b.String())
+	}
+}
+
+func TestConferenceStandings(t *testing.T) {
+	a := bts.Team{Name4: "A"}
+	b := bts.Team{Name4: "B"}
+	c := bts.Team{Name4: "C"}
+	d := bts.Team{Name4: "D"}
+	s := bts.ScheduleFromNotation(map[string][]string{
+		"A": {"B", "C", "D"},
+		"B": {"@A", "D", "C"},
+		"C": {"D", "@A", "@B"},
+		"D": {"@C", "@B", "@A"},
+	})
+	// Ratings so far apart that every game is decided by rating.
+	ratings := map[bts.Team]float64{a: 300, b: 200, c: 100, d: 0}
+	model := bts.NewGaussianSpreadModel(ratings, 1, 0, 0)
+	ss := newSeasonSimulator(s, model, 1, 0)
+
+	confs := []conference{{
+		name:         "Conf",
+		championship: true,
+		divisions: []division{
+			{conference: "Conf", name: "North", teams: []bts.Team{c, a}},
+			{conference: "Conf", name: "South", teams: []bts.Team{d, b}},
+		},
+	}}
+	if err := ss.setConferences(confs); err != nil {
+		t.Fatal(err)
+	}
+	if len(ss.conferences[0].games) != 6 {
+		t.Fatalf("expected 6 conference games, got %d", len(ss.conferences[0].games))
+	}
+
+	_, standings := ss.run(100, 2, 0)
+	want := []divisionResults{
+		{Conference: "Conf", Division: "North", Teams: []bts.Team{c, a}, Places: [][]float64{{0, 1}, {1, 0}}, Championship: []float64{0, 1}},
+		{Conference: "Conf", Division: "South", Teams: []bts.Team{d, b}, Places: [][]float64{{0, 1}, {1, 0}}, Championship: []float64{0, 1}},
+	}
+	if !reflect.DeepEqual(standings, want) {
+		t.Errorf("expected standings %v, got %v", want, standings)
+	}
+}
+
+func TestSetOutcomes(t *testing.T) {
+	win, loss := 1, 0
+	names := map[string]bts.Team{"A": {Name4: "A"}, "B": {Name4: "B"}}
+
+	ss := testSimulator()
+	if err := ss.setOutcomes(OutcomesYaml{"A": {{&win}, {&loss}, nil}, "B": {{&loss}}}, names); err != nil {
+		t.Fatal(err)
+	}
+	if ss.fixed != 2 {
+		t.Errorf("expected 2 fixed games, got %d", ss.fixed)
+	}
+	results, _ := ss.run(1000, 2, 0)
+	// A beat B and lost to C, and has a bye in the last week.
+	if !reflect.DeepEqual(results[0].WinProbabilities, []float64{0, 1, 0}) {
+		t.Errorf("expected A to win exactly 1 game, got %v", results[0].WinProbabilities)
+	}
+
+	ss = testSimulator()
+	if err := ss.setOutcomes(OutcomesYaml{"A": {{&win}}, "B": {{&win}}}, names); err == nil {
+		t.Errorf("expected error for conflicting outcomes")
+	}
+	ss = testSimulator()
+	if err := ss.setOutcomes(OutcomesYaml{"A": {nil, nil, {&win}}}, names); err != nil || ss.fixed != 0 {
+		t.Errorf("expected outcome in bye week to be ignored, got %d fixed games and error %v", ss.fixed, err)
+	}
+	ss = testSimulator()
+	names["C"] = bts.Team{Name4: "C"}
+	if err := ss.setOutcomes(OutcomesYaml{"C": {nil, {&win}}}, names); err == nil {
+		t.Errorf("expected error for outcomes of a team that is not a contender")
+	}
+}
+
+func TestSetOutcomesMultipleGames(t *testing.T) {
+	a := bts.Team{Name4: "A"}
+	b := bts.Team{Name4: "B"}
+	c := bts.Team{Name4: "C"}
+	s := bts.ScheduleFromNotation(map[string][]string{
+		"A": {"B+@C", "C"},
+		"B": {"@A", ""},
+		"C": {"A", "@A"},
+	})
+	model := bts.NewGaussianSpreadModel(map[bts.Team]float64{a: 10, b: 5, c: 0}, 14, 3, 1.5)
+	names := map[string]bts.Team{"A": a, "B": b, "C": c}
+
+	var oy OutcomesYaml
+	if err := yaml.Unmarshal([]byte("A: [[1, 0], ~]\nC: [1, 0]\n"), &oy); err != nil {
+		t.Fatal(err)
+	}
+	ss := newSeasonSimulator(s, model, 14, 4.7)
+	if len(ss.games) != 3 {
+		t.Fatalf("expected 3 distinct games, got %d", len(ss.games))
+	}
+	if err := ss.setOutcomes(oy, names); err != nil {
+		t.Fatal(err)
+	}
+	if ss.fixed != 3 {
+		t.Errorf("expected 3 fixed games, got %d", ss.fixed)
+	}
+	results, _ := ss.run(1000, 2, 0)
+	// A beat B, lost to C, then beat C.
+	if !reflect.DeepEqual(results[0].WinProbabilities, []float64{0, 0, 1, 0}) {
+		t.Errorf("expected A to win exactly 2 games, got %v", results[0].WinProbabilities)
+	}
+
+	win := 1
+	ss = newSeasonSimulator(s, model, 14, 4.7)
+	if err := ss.setOutcomes(OutcomesYaml{"A": {{&win}}}, names); err == nil {
+		t.Errorf("expected error for a single outcome given for two games")
+	}
+}
+
+func TestCancelledGames(t *testing.T) {
+	a := bts.Team{Name4: "A"}
+	b := bts.Team{Name4: "B"}
+	s := bts.ScheduleFromNotation(map[string][]string{
+		"A": {"B", "@B"},
+		"B": {"@A", "A"},
+	})
+	s.Get(a, 1).SetStatus(bts.Cancelled)
+	s.Get(b, 1).SetStatus(bts.Cancelled)
+	model := bts.NewGaussianSpreadModel(map[bts.Team]float64{a: 10, b: 0}, 14, 3, 1.5)
+
+	ss := newSeasonSimulator(s, model, 14, 4.7)
+	if len(ss.games) != 1 || ss.played[0] != 1 {
+		t.Fatalf("expected only the game played as scheduled to be simulated, got %d games and %d played", len(ss.games), ss.played[0])
+	}
+	win := 1
+	if err := ss.setOutcomes(OutcomesYaml{"A": {{&win}, {&win}}}, map[string]bts.Team{"A": a, "B": b}); err != nil {
+		t.Fatal(err)
+	}
+	if ss.fixed != 1 {
+		t.Errorf("expected outcome of the cancelled game to be ignored, got %d fixed games", ss.fixed)
+	}
+}
+
+func TestSortScores(t *testing.T) {
+	scores := defaultScoringRules.scoreTeams([]teamResults{
+		makeTeamResults(bts.Team{Name4: "B"}, []float64{.5, .5}),
+		makeTeamResults(bts.Team{Name4: "C"}, []float64{0, 1}),
+		makeTeamResults(bts.Team{Name4: "A"}, []float64{.5, .5}),
+	})
+	order := func() string {
+		var b strings.Builder
+		for _, s := range scores {
+			b.WriteString(s.Team.Name())
+		}
+		return b.String()
+	}
+
+	if err := sortScores(scores, "team"); err != nil || order() != "ABC" {
+		t.Errorf("expected order ABC by team, got %s (error %v)", order(), err)
+	}
+	if err := sortScores(scores, "wins"); err != nil || order() != "CAB" {
+		t.Errorf("expected order CAB by wins, got %s (error %v)", order(), err)
+	}
+	if err := sortScores(scores, "bogus"); err == nil {
+		t.Errorf("expected error for unknown sort")
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	scores := defaultScoringRules.scoreTeams([]teamResults{makeTeamResults(bts.Team{Name4: "A"}, []float64{.25, .75})})
+	for _, format := range []string{"table", "csv", "json", "markdown"} {
+		var b strings.Builder
+		if err := writeOutput(&b, format, scores, nil, nil); err != nil {
+			t.Errorf("format %s: %v", format, err)
+		}
+		if !strings.Contains(b.String(), "A") {
+			t.Errorf("format %s: expected output to contain team A, got\n%s", format, b.String())
+		}
+	}
+	if err := writeOutput(io.Discard, "bogus", scores, nil, nil); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+	if _, err := formatForFile("out.xlsx"); err == nil {
+		t.Errorf("expected error for unknown export extension")
+	}
+}
+
+func TestLoadOffline(t *testing.T) {
+	f, err := ioutil.TempFile(t.TempDir(), "ratings*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "home_advantage: 2\nbias: 1\nstd_dev: 10\nratings: {AAA: 80, BBB: 70}")
+	f.Close()
+
+	inputs, err := loadOffline(f.Name(), YamlSchedule{"AAA": {{{Opponent: "@BBB"}}, {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := bts.Team{Name4: "AAA"}
+	if inputs.teamsByName["AAA"] != a || inputs.std != 10 {
+		t.Errorf("unexpected inputs %+v", inputs)
+	}
+	// AAA is rated 10 points higher but gives up 3 points on the road.
+	if _, spread := inputs.model.Predict(inputs.schedule.Get(a, 0)); spread != 7 {
+		t.Errorf("expected spread 7, got %f", spread)
+	}
+	if inputs.schedule.Get(a, 1).Team(1) != bts.BYE {
+		t.Errorf("expected bye in week 1, got %v", inputs.schedule.Get(a, 1))
+	}
+
+	if _, err := loadOffline(f.Name(), YamlSchedule{"AAA": {{{Opponent: "CCC"}}}}); err == nil {
+		t.Errorf("expected error for unrated team")
+	}
+}
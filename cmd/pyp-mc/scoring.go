@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"gopkg.in/yaml.v2"
+)
+
+// Bonus awards extra points to a pony that wins at least a given number of games.
+type Bonus struct {
+	Wins   int     `yaml:"wins" json:"wins"`
+	Points float64 `yaml:"points" json:"points"`
+}
+
+// ScoringRules are the pick-your-pony scoring rules.
+type ScoringRules struct {
+	// PointsPerWin is the number of points scored for each game the pony wins.
+	PointsPerWin float64 `yaml:"points_per_win"`
+	// Bonuses are awarded in addition to the points per win. Every bonus whose threshold is reached is awarded.
+	Bonuses []Bonus `yaml:"bonuses"`
+	// Pickers maps each picker to the names of the teams available to them, as named in the schedule.
+	// Pickers with no teams listed may pick any team.
+	Pickers map[string][]string `yaml:"pickers"`
+}
+
+// defaultScoringRules scores one point per win with no bonuses and no pickers.
+var defaultScoringRules = ScoringRules{PointsPerWin: 1}
+
+// loadScoringRules parses a YAML file of scoring rules. Rules missing from the file keep their default values.
+func loadScoringRules(fileName string) (*ScoringRules, error) {
+	yf, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	sr := defaultScoringRules
+	if err := yaml.Unmarshal(yf, &sr); err != nil {
+		return nil, err
+	}
+	for _, b := range sr.Bonuses {
+		if b.Wins < 0 {
+			return nil, fmt.Errorf("bonus of %f points: wins must not be negative, got %d", b.Points, b.Wins)
+		}
+	}
+	return &sr, nil
+}
+
+// score calculates the points scored by a pony that wins the given number of games.
+func (sr ScoringRules) score(wins int) float64 {
+	points := float64(wins) * sr.PointsPerWin
+	for _, b := range sr.Bonuses {
+		if wins >= b.Wins {
+			points += b.Points
+		}
+	}
+	return points
+}
+
+// teamScore is the distribution of points scored by picking a team as a pony.
+type teamScore struct {
+	teamResults
+	ExpectedScore float64
+	ScoreVariance float64
+}
+
+// scoreTeams calculates the expected score and variance of the score of every team from its distribution of wins.
+func (sr ScoringRules) scoreTeams(results []teamResults) []teamScore {
+	scores := make([]teamScore, len(results))
+	for i, r := range results {
+		mean := 0.
+		meanSq := 0.
+		for wins, p := range r.WinProbabilities {
+			s := sr.score(wins)
+			mean += p * s
+			meanSq += p * s * s
+		}
+		scores[i] = teamScore{teamResults: r, ExpectedScore: mean, ScoreVariance: meanSq - mean*mean}
+	}
+	return scores
+}
+
+// recommendation is the best pony available to a picker.
+type recommendation struct {
+	Picker        string  `json:"picker"`
+	Team          string  `json:"team"`
+	ExpectedScore float64 `json:"expected_score"`
+	ScoreVariance float64 `json:"score_variance"`
+}
+
+// recommend picks the team with the highest expected score available to each picker, breaking ties by lower variance.
+// Team names are looked up by the names used in the schedule. Team names not in the schedule are an error.
+func (sr ScoringRules) recommend(scores []teamScore, teamsByName map[string]bts.Team) ([]recommendation, error) {
+	pickers := make([]string, 0, len(sr.Pickers))
+	for picker := range sr.Pickers {
+		pickers = append(pickers, picker)
+	}
+	sort.Strings(pickers)
+
+	recs := make([]recommendation, 0, len(pickers))
+	for _, picker := range pickers {
+		available := make(map[bts.Team]bool)
+		for _, name := range sr.Pickers[picker] {
+			team, ok := teamsByName[name]
+			if !ok {
+				return nil, fmt.Errorf("picker %s: team \"%s\" not in schedule", picker, name)
+			}
+			available[team] = true
+		}
+
+		var best *teamScore
+		for i := range scores {
+			s := &scores[i]
+			if len(available) > 0 && !available[s.Team] {
+				continue
+			}
+			if best == nil || s.ExpectedScore > best.ExpectedScore || (s.ExpectedScore == best.ExpectedScore && s.ScoreVariance < best.ScoreVariance) {
+				best = s
+			}
+		}
+		if best == nil {
+			return nil, fmt.Errorf("no team available to picker %s is in the schedule", picker)
+		}
+		recs = append(recs, recommendation{Picker: picker, Team: best.Team.Name(), ExpectedScore: best.ExpectedScore, ScoreVariance: best.ScoreVariance})
+	}
+	return recs, nil
+}
+
+// printScores writes a table of the expected score of each team followed by the recommended pony of each picker.
+func printScores(w io.Writer, scores []teamScore, recs []recommendation) {
+	fmt.Fprintln(w, "Scores")
+	fmt.Fprintf(w, " %15s  %8s  %8s \n", "Team", "E[Score]", "Var")
+	for _, s := range scores {
+		fmt.Fprintf(w, " %15s  %8.3f  %8.3f \n", s.Team.Name(), s.ExpectedScore, s.ScoreVariance)
+	}
+
+	if len(recs) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Recommendations")
+	for _, r := range recs {
+		fmt.Fprintf(w, " %15s: %s (E[Score] %.3f, Var %.3f)\n", r.Picker, r.Team, r.ExpectedScore, r.ScoreVariance)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/atgjack/prob"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+)
+
+// simGame is a single game simulated once per season.
+// Games between two contenders appear in both of their schedules, but are only simulated once so both teams see the same outcome.
+type simGame struct {
+	// team1 and team2 index the rating perturbations of the teams playing.
+	team1, team2 int
+	// spread is the predicted spread in favor of team1 before ratings are perturbed.
+	spread float64
+	// credit1 and credit2 index the contenders credited with a win when team1 or team2 wins, or are -1 if the winner is not a contender.
+	credit1, credit2 int
+	// week is the week the game is played.
+	week int
+	// order1 and order2 are the positions of the game among the games credit1 and credit2 play that week.
+	order1, order2 int
+	// outcome is the result of the game if it has already been played.
+	outcome gameOutcome
+}
+
+// gameKey identifies a game by week and the teams playing, in either order.
+// Teams that play each other more than once in a week are told apart by n, which counts their earlier games that week.
+type gameKey struct {
+	week   int
+	t1, t2 bts.Team
+	n      int
+}
+
+func makeGameKey(week int, a, b bts.Team, n int) gameKey {
+	if b.Name() < a.Name() {
+		a, b = b, a
+	}
+	return gameKey{week: week, t1: a, t2: b, n: n}
+}
+
+// seasonSimulator simulates the number of wins of every contender in a schedule.
+// Each simulated season perturbs the rating of every team once, then plays every game with the perturbed ratings.
+type seasonSimulator struct {
+	teams  bts.TeamList
+	played []int
+	// scheduled counts the games each contender is scheduled to play in each week, including games that are not simulated.
+	scheduled     [][]int
+	games         []simGame
+	conferences   []conference
+	fixed         int
+	nRated        int
+	dist          prob.Normal
+	hypervariance float64
+}
+
+// newSeasonSimulator prepares the games of a schedule for simulation.
+// The model gives the unperturbed spread of each game, and std is the standard deviation of the actual spread around the prediction.
+// Ratings are perturbed by a normal random variable with standard deviation hypervariance.
+func newSeasonSimulator(s bts.Schedule, model bts.PredictionModel, std, hypervariance float64) *seasonSimulator {
+	teams := s.TeamList()
+	sort.Sort(teams)
+	rated := make(map[bts.Team]int)
+	ratedIndex := func(t bts.Team) int {
+		i, ok := rated[t]
+		if !ok {
+			i = len(rated)
+			rated[t] = i
+		}
+		return i
+	}
+
+	games := make([]simGame, 0)
+	played := make([]int, len(teams))
+	scheduled := make([][]int, len(teams))
+	seen := make(map[gameKey]int)
+	for i, t := range teams {
+		scheduled[i] = make([]int, s.NumWeeks())
+		for week := 0; week < s.NumWeeks(); week++ {
+			scheduled[i][week] = len(s.Games(t, week))
+			// Byes have no games: they can neither be won nor lost.
+			met := make(map[bts.Team]int)
+			for order, game := range s.Games(t, week) {
+				opponent := game.Team(1)
+				if opponent == bts.BYE || opponent == bts.NONE {
+					continue
+				}
+				// Neither are games that will not be played or do not count.
+				if status := game.Status(); status == bts.Cancelled || status == bts.NoContest {
+					continue
+				}
+				played[i]++
+
+				key := makeGameKey(week, t, opponent, met[opponent])
+				met[opponent]++
+				if g, ok := seen[key]; ok {
+					// The opponent already scheduled this game: credit this team when the opponent loses.
+					games[g].credit2 = i
+					games[g].order2 = order
+					continue
+				}
+
+				_, spread := model.Predict(game)
+				seen[key] = len(games)
+				games = append(games, simGame{
+					team1:   ratedIndex(t),
+					team2:   ratedIndex(opponent),
+					spread:  spread,
+					credit1: i,
+					credit2: -1,
+					week:    week,
+					order1:  order,
+				})
+			}
+		}
+	}
+
+	return &seasonSimulator{
+		teams:         teams,
+		played:        played,
+		scheduled:     scheduled,
+		games:         games,
+		nRated:        len(rated),
+		dist:          prob.Normal{Mu: 0, Sigma: std},
+		hypervariance: hypervariance,
+	}
+}
+
+// simulate plays n seasons using the given RNG and returns the histogram of wins for each contender, from zero wins to winning every game played,
+// along with the tally of conference standings. Ties in the standings are broken with their own RNG, so that tallying standings does not change the games played.
+func (ss *seasonSimulator) simulate(rng, tiebreaks *rand.Rand, n int) ([][]int, *standingsTally) {
+	hist := make([][]int, len(ss.teams))
+	for i := range hist {
+		hist[i] = make([]int, ss.played[i]+1)
+	}
+	st := ss.newStandingsTally()
+
+	deltas := make([]float64, ss.nRated)
+	wins := make([]int, len(ss.teams))
+	won := make([]bool, len(ss.games))
+	for itr := 0; itr < n; itr++ {
+		// nudge ratings by a random amount
+		for i := range deltas {
+			deltas[i] = rng.NormFloat64() * ss.hypervariance
+		}
+
+		for i := range wins {
+			wins[i] = 0
+		}
+		for gi, g := range ss.games {
+			switch g.outcome {
+			case team1Won:
+				won[gi] = true
+			case team2Won:
+				won[gi] = false
+			default:
+				p := ss.dist.Cdf(g.spread + deltas[g.team1] - deltas[g.team2])
+				won[gi] = rng.Float64() < p
+			}
+			if won[gi] {
+				if g.credit1 >= 0 {
+					wins[g.credit1]++
+				}
+			} else if g.credit2 >= 0 {
+				wins[g.credit2]++
+			}
+		}
+
+		for i, w := range wins {
+			hist[i][w]++
+		}
+
+		if len(ss.conferences) > 0 {
+			ss.tallyStandings(tiebreaks, st, won, wins)
+		}
+	}
+
+	return hist, st
+}
+
+// nStreams is the number of independent streams of random numbers the simulated seasons are split among.
+// It is fixed so that results do not depend on the number of workers.
+const nStreams = 64
+
+// run splits n seasons among nStreams streams, each with its own RNGs seeded in turn from the given seed, and plays the streams with a pool of workers.
+// The histograms of every stream are summed, so results depend only on the seed and n.
+func (ss *seasonSimulator) run(n, workers int, seed int64) ([]teamResults, []divisionResults) {
+	if workers < 1 {
+		workers = 1
+	}
+	src := rand.NewSource(seed)
+	hists := make([][][]int, nStreams)
+	tallies := make([]*standingsTally, nStreams)
+
+	type stream struct {
+		i, n           int
+		rng, tiebreaks *rand.Rand
+	}
+	streams := make(chan stream, nStreams)
+	for i := 0; i < nStreams; i++ {
+		ns := n / nStreams
+		if i < n%nStreams {
+			ns++
+		}
+		rng := rand.New(rand.NewSource(src.Int63()))
+		tiebreaks := rand.New(rand.NewSource(src.Int63()))
+		streams <- stream{i: i, n: ns, rng: rng, tiebreaks: tiebreaks}
+	}
+	close(streams)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range streams {
+				hists[s.i], tallies[s.i] = ss.simulate(s.rng, s.tiebreaks, s.n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	results := make([]teamResults, len(ss.teams))
+	for i, t := range ss.teams {
+		probs := make([]float64, ss.played[i]+1)
+		for _, hist := range hists {
+			for wins, count := range hist[i] {
+				probs[wins] += float64(count)
+			}
+		}
+		for wins := range probs {
+			probs[wins] /= float64(n)
+		}
+		results[i] = makeTeamResults(t, probs)
+	}
+
+	st := ss.newStandingsTally()
+	for _, tally := range tallies {
+		st.merge(tally)
+	}
+	return results, ss.standingsResults(st, n)
+}
+
+// teamResults summarizes the simulated wins of a team.
+type teamResults struct {
+	Team bts.Team
+	// Games is the number of games the team plays, excluding byes.
+	Games int
+	// WinProbabilities is the probability of winning exactly k games, for k from 0 to Games.
+	WinProbabilities []float64
+	// AtLeast is the probability of winning at least k games, for k from 0 to Games.
+	AtLeast []float64
+	// ExpectedWins is the expected number of games won.
+	ExpectedWins float64
+	// MedianWins is the smallest number of wins k such that the probability of winning k or fewer games is at least one half.
+	MedianWins int
+}
+
+// makeTeamResults summarizes the probability of each number of wins.
+func makeTeamResults(t bts.Team, probs []float64) teamResults {
+	r := teamResults{Team: t, Games: len(probs) - 1, WinProbabilities: probs, AtLeast: make([]float64, len(probs))}
+
+	tail := 0.
+	for k := len(probs) - 1; k >= 0; k-- {
+		tail += probs[k]
+		r.AtLeast[k] = tail
+		r.ExpectedWins += float64(k) * probs[k]
+	}
+
+	cum := 0.
+	for k, p := range probs {
+		cum += p
+		if cum >= .5 {
+			r.MedianWins = k
+			break
+		}
+	}
+
+	return r
+}
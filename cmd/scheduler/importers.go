@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+)
+
+// dateFormat is the format of dates in CSV schedules and of the season start flag.
+const dateFormat = "2006-01-02"
+
+// importedGame is a single game read from a CSV or iCalendar schedule.
+type importedGame struct {
+	// kickoff is the date and, if known, time of the game.
+	kickoff time.Time
+	home    string
+	away    string
+	neutral bool
+	// venue is the name of the venue where the game is played, if known.
+	venue string
+}
+
+// readCSV reads games from a CSV file with a header row.
+// The columns "date", "home", and "away" are required, and "neutral" and "venue" are optional.
+// Dates are formatted as YYYY-MM-DD, optionally followed by a kickoff time (see bts.ParseKickoff), and the neutral flag is any value understood by strconv.ParseBool (empty means false).
+func readCSV(r io.Reader) ([]importedGame, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("readCSV: reading header: %v", err)
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"date", "home", "away"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("readCSV: missing required column \"%s\"", required)
+		}
+	}
+	neutralCol, hasNeutral := cols["neutral"]
+	venueCol, hasVenue := cols["venue"]
+
+	games := make([]importedGame, 0)
+	// line counts records, starting after the header.
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("readCSV: %v", err)
+		}
+
+		kickoff, err := bts.ParseKickoff(strings.TrimSpace(record[cols["date"]]))
+		if err != nil {
+			return nil, fmt.Errorf("readCSV: line %d: %v", line, err)
+		}
+		g := importedGame{
+			kickoff: kickoff,
+			home:    strings.TrimSpace(record[cols["home"]]),
+			away:    strings.TrimSpace(record[cols["away"]]),
+		}
+		if hasNeutral {
+			if n := strings.TrimSpace(record[neutralCol]); n != "" {
+				g.neutral, err = strconv.ParseBool(n)
+				if err != nil {
+					return nil, fmt.Errorf("readCSV: line %d: neutral flag: %v", line, err)
+				}
+			}
+		}
+		if hasVenue {
+			g.venue = strings.TrimSpace(record[venueCol])
+		}
+		if g.home == "" || g.away == "" {
+			return nil, fmt.Errorf("readCSV: line %d: home and away teams are required", line)
+		}
+		games = append(games, g)
+	}
+	return games, nil
+}
+
+// readICS reads games from the VEVENT components of an iCalendar file.
+// The kickoff time of each game is taken from DTSTART and the teams from SUMMARY, which must be of the form
+// "AWAY at HOME" or "AWAY @ HOME" for a game at the home team's field, or "TEAM1 vs. TEAM2" (or "vs") for a neutral-site game.
+// The venue of each game is taken from LOCATION, if present.
+func readICS(r io.Reader) ([]importedGame, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	games := make([]importedGame, 0)
+	var inEvent bool
+	var start, summary, location string
+	for i, line := range lines {
+		name, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, summary, location = "", "", ""
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if start == "" || summary == "" {
+				return nil, fmt.Errorf("readICS: event ending on line %d is missing DTSTART or SUMMARY", i+1)
+			}
+			kickoff, err := parseICSTime(start)
+			if err != nil {
+				return nil, fmt.Errorf("readICS: event ending on line %d: %v", i+1, err)
+			}
+			g, err := parseSummary(summary)
+			if err != nil {
+				return nil, fmt.Errorf("readICS: event ending on line %d: %v", i+1, err)
+			}
+			g.kickoff = kickoff
+			g.venue = location
+			games = append(games, g)
+		case inEvent && strings.HasPrefix(name, "DTSTART"):
+			start = line
+		case inEvent && name == "SUMMARY":
+			summary = value
+		case inEvent && name == "LOCATION":
+			location = value
+		}
+	}
+	return games, nil
+}
+
+// unfoldICS splits an iCalendar file into content lines, joining lines folded onto continuation lines that start with whitespace.
+func unfoldICS(r io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("readICS: %v", err)
+	}
+	return lines, nil
+}
+
+// splitICSLine splits a content line into its upper-case property name, without parameters, and its unescaped value.
+func splitICSLine(line string) (string, string) {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return "", ""
+	}
+	name := line[:colon]
+	if semi := strings.IndexByte(name, ';'); semi >= 0 {
+		name = name[:semi]
+	}
+	value := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(line[colon+1:])
+	return strings.ToUpper(name), strings.TrimSpace(value)
+}
+
+// parseICSTime parses a DTSTART content line as either a date or a date-time.
+// Date-times are in UTC if they end in "Z", in the time zone given by the TZID parameter if it is known, or otherwise in UTC.
+func parseICSTime(line string) (time.Time, error) {
+	colon := strings.IndexByte(line, ':')
+	params, value := line[:colon], strings.TrimSpace(line[colon+1:])
+	if len(value) == 8 {
+		return time.Parse("20060102", value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	loc := time.UTC
+	for _, param := range strings.Split(params, ";")[1:] {
+		if strings.HasPrefix(strings.ToUpper(param), "TZID=") {
+			if l, err := time.LoadLocation(strings.Trim(param[len("TZID="):], `"`)); err == nil {
+				loc = l
+			}
+		}
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+// parseSummary parses the teams playing from the summary of an event.
+func parseSummary(summary string) (importedGame, error) {
+	for _, sep := range []string{" at ", " @ "} {
+		if i := strings.Index(summary, sep); i >= 0 {
+			return importedGame{
+				away: strings.TrimSpace(summary[:i]),
+				home: strings.TrimSpace(summary[i+len(sep):]),
+			}, nil
+		}
+	}
+	for _, sep := range []string{" vs. ", " vs "} {
+		if i := strings.Index(summary, sep); i >= 0 {
+			return importedGame{
+				home:    strings.TrimSpace(summary[:i]),
+				away:    strings.TrimSpace(summary[i+len(sep):]),
+				neutral: true,
+			}, nil
+		}
+	}
+	return importedGame{}, fmt.Errorf("summary \"%s\" does not name two teams", summary)
+}
+
+// weekOf calculates the week index of a kickoff, counting whole weeks between the calendar date of the kickoff (in its own time zone)
+// and the calendar date of the start of the season. Week 0 begins at the start of the season.
+func weekOf(kickoff, seasonStart time.Time) int {
+	return int(calendarDate(kickoff).Sub(calendarDate(seasonStart)).Hours() / (24 * 7))
+}
+
+// calendarDate strips the time of day and time zone from a time.
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// buildSchedule arranges games into a schedule for every team that plays, with byes in weeks a team does not play.
+// Teams are named as in the imported games, and may play any number of games in a week. Games before the start of the season are errors,
+// and games listed more than once are only counted once.
+// If a venue registry is given, the location of every game with a venue is inferred from the distance each team travels to it.
+func buildSchedule(games []importedGame, seasonStart time.Time, venues *bts.VenueRegistry) (bts.Schedule, error) {
+	nWeeks := 0
+	for _, g := range games {
+		if calendarDate(g.kickoff).Before(calendarDate(seasonStart)) {
+			return nil, fmt.Errorf("buildSchedule: game %s at %s on %s is before the season starts on %s", g.away, g.home, g.kickoff.Format(dateFormat), seasonStart.Format(dateFormat))
+		}
+		if w := weekOf(g.kickoff, seasonStart) + 1; w > nWeeks {
+			nWeeks = w
+		}
+	}
+
+	s := make(bts.Schedule)
+	add := func(team bts.Team, week int, game *bts.Game) {
+		if _, ok := s[team]; !ok {
+			s[team] = make([][]*bts.Game, nWeeks)
+			for i := range s[team] {
+				s[team][i] = make([]*bts.Game, 0, 1)
+			}
+		}
+		s[team][week] = append(s[team][week], game)
+	}
+
+	seen := make(map[string]bool)
+	for _, g := range games {
+		week := weekOf(g.kickoff, seasonStart)
+		key := fmt.Sprintf("%d %s %s", week, g.home, g.away)
+		if g.neutral && g.away < g.home {
+			// Neutral-site games may list either team first.
+			key = fmt.Sprintf("%d %s %s", week, g.away, g.home)
+		}
+		if seen[key] {
+			// Calendars exported from each team's schedule list every game twice.
+			continue
+		}
+		seen[key] = true
+
+		home := bts.Team{Name4: g.home}
+		away := bts.Team{Name4: g.away}
+		homeGame, awayGame, err := importGames(home, away, g, venues)
+		if err != nil {
+			return nil, err
+		}
+		add(home, week, homeGame)
+		add(away, week, awayGame)
+	}
+	return s, nil
+}
+
+// importGames makes the game from the point of view of the home team and of the away team.
+func importGames(home, away bts.Team, g importedGame, venues *bts.VenueRegistry) (*bts.Game, *bts.Game, error) {
+	if venues == nil || g.venue == "" {
+		homeLoc := bts.RelativeLocation(bts.Home)
+		if g.neutral {
+			homeLoc = bts.Neutral
+		}
+		homeGame, awayGame := bts.NewGame(home, away, homeLoc), bts.NewGame(away, home, -homeLoc)
+		homeGame.SetKickoff(g.kickoff)
+		awayGame.SetKickoff(g.kickoff)
+		homeGame.SetVenue(g.venue)
+		awayGame.SetVenue(g.venue)
+		return homeGame, awayGame, nil
+	}
+
+	venue, ok := venues.Venue(g.venue)
+	if !ok {
+		return nil, nil, fmt.Errorf("buildSchedule: game %s at %s on %s: unknown venue \"%s\"", g.away, g.home, g.kickoff.Format(dateFormat), g.venue)
+	}
+	homeGame, err := venues.Game(home, away, venue)
+	if err != nil {
+		return nil, nil, fmt.Errorf("buildSchedule: game %s at %s on %s: %v", g.away, g.home, g.kickoff.Format(dateFormat), err)
+	}
+	awayGame, err := venues.Game(away, home, venue)
+	if err != nil {
+		return nil, nil, fmt.Errorf("buildSchedule: game %s at %s on %s: %v", g.away, g.home, g.kickoff.Format(dateFormat), err)
+	}
+	homeGame.SetKickoff(g.kickoff)
+	awayGame.SetKickoff(g.kickoff)
+	return homeGame, awayGame, nil
+}
+
+// makeYamlSchedule converts a schedule to the YAML representation, with empty weeks for byes.
+func makeYamlSchedule(s bts.Schedule) YamlSchedule {
+	ys := make(YamlSchedule)
+	for team, games := range s {
+		weeks := make([]bts.ScheduleWeek, len(games))
+		for i := range games {
+			weeks[i] = s.Week(team, i)
+		}
+		ys[team.Name()] = weeks
+	}
+	return ys
+}
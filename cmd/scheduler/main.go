@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -24,8 +26,22 @@ var otherTeams = make(map[string]*firestore.DocumentRef)
 // byeWeekTeam is a team representing a fake bye week
 var byeWeekTeam *firestore.DocumentRef
 
+var csvFlag = flag.String("csv", "", "Import the schedule from a CSV file of games with columns date, home, away, and (optionally) neutral.")
+var icsFlag = flag.String("ics", "", "Import the schedule from an iCalendar file of games.")
+var startFlag = flag.String("start", "", "Start date of the season (YYYY-MM-DD), used to compute the week of imported games. Defaults to the start of the most recent season in Firestore.")
+var outFlag = flag.String("out", "", "Write the schedule as YAML to this file (\"-\" for standard output) instead of uploading it to Firestore.")
+var formatFlag = flag.String("format", "yaml", "Format of the schedule written with -out: yaml, csv, markdown, or html.")
+var storedFlag = flag.Bool("stored", false, "Read the latest schedule stored for the season from Firestore instead of a file. Requires -out.")
+var venuesFlag = flag.String("venues", "", "Venue registry YAML file of team home stadiums and neutral-site venues. When given, the locations of imported games with venues are inferred from distance.")
+var dryRunFlag = flag.Bool("dry-run", false, "Print the week-by-week differences between the schedule and the latest schedule stored for the season instead of uploading it.")
+var updateFlag = flag.Bool("update", false, "Revise the latest schedule stored for the season in place instead of creating a new schedule.")
+
+// teamNames is a mapping of team DocumentRef IDs to team names.
+var teamNames = make(map[string]string)
+
 // Team represents how teams are stored in Firestore
 type Team struct {
+	Name4      string   `firestore:"name_4"`
 	OtherNames []string `firestore:"other_names"`
 }
 
@@ -49,6 +65,8 @@ func loadTeams(ctx context.Context) {
 			panic(err)
 		}
 
+		teamNames[teamDoc.Ref.ID] = team.Name4
+
 		// store by other name
 		for _, name := range team.OtherNames {
 			if _, exists := otherTeams[name]; exists {
@@ -61,16 +79,20 @@ func loadTeams(ctx context.Context) {
 	}
 }
 
-// mostRecentSeason gets the most recent season from firestore
-func mostRecentSeason(ctx context.Context) (*firestore.DocumentRef, error) {
+// mostRecentSeason gets the most recent season and its start time from firestore
+func mostRecentSeason(ctx context.Context) (*firestore.DocumentRef, time.Time, error) {
 	docItr := fsclient.Collection("seasons").OrderBy("start", firestore.Desc).Limit(1).Documents(ctx)
 	defer docItr.Stop()
 	seasonDoc, err := docItr.Next()
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
 	}
 	log.Printf("most recent season on record: \"%s\"", seasonDoc.Ref.ID)
-	return seasonDoc.Ref, nil
+	start, err := seasonDoc.DataAt("start")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return seasonDoc.Ref, start.(time.Time), nil
 }
 
 // splitLocTeam splits a marked team name into a relative location and a team name.
@@ -95,56 +117,195 @@ func splitLocTeam(locTeam string) (bts.RelativeLocation, string) {
 }
 
 // YamlSchedule is a representation of a YAML schedule file
-type YamlSchedule map[string][]string
+type YamlSchedule map[string][]bts.ScheduleWeek
 
-// Schedule represents how the data are stored in firestore
+// Schedule represents how the data are stored in firestore.
+// Opponents, locales, kickoffs, and venues hold one entry per game, with a game against the bye week team for each bye.
+// Weeks records the week of each entry, and is stored only if a team plays more than one game in some week; otherwise entry i is week i.
+// Kickoffs and venues are stored only if at least one game has one, with zero times and empty names where unknown,
+// and statuses are stored only if at least one game is not played as scheduled.
+// Travel and opponent travel are the distances in miles the team and the opponent travel to each game (see bts.VenueRegistry),
+// stored only if at least one game has them, with -1 where unknown.
 type Schedule struct {
 	Team      *firestore.DocumentRef   `firestore:"team"`
 	Opponents []*firestore.DocumentRef `firestore:"opponents"`
 	Locales   []int                    `firestore:"locales"`
+	Weeks     []int                    `firestore:"weeks,omitempty"`
+	Kickoffs  []time.Time              `firestore:"kickoffs,omitempty"`
+	Venues    []string                 `firestore:"venues,omitempty"`
+	Statuses  []string                 `firestore:"statuses,omitempty"`
+
+	Travel         []float64 `firestore:"travel,omitempty"`
+	OpponentTravel []float64 `firestore:"opponent_travel,omitempty"`
 }
 
-// SeasonSchedule represents a document in firestore that contains team schedules
+// SeasonSchedule represents a document in firestore that contains team schedules.
+// Each revision of a season's schedule increments the version and sets the timestamp to the time of the revision.
 type SeasonSchedule struct {
 	Season    *firestore.DocumentRef `firestore:"season"`
+	Version   int                    `firestore:"version"`
 	Timestamp time.Time              `firestore:"timestamp,serverTimestamp"`
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatalf("schedule to parse must be passed as an argument")
-		os.Exit(1)
+// readSchedule reads the schedule from whichever source was given on the command line.
+// Imported schedules need the start of the season to compute the week of each game: start is called to get it only when needed.
+func readSchedule(start func() (time.Time, error)) (YamlSchedule, error) {
+	var games []importedGame
+	var err error
+	switch {
+	case *csvFlag != "" && *icsFlag != "":
+		return nil, fmt.Errorf("only one of -csv and -ics may be given")
+	case *csvFlag != "":
+		log.Printf("importing CSV schedule \"%s\"", *csvFlag)
+		games, err = readFile(*csvFlag, readCSV)
+	case *icsFlag != "":
+		log.Printf("importing iCalendar schedule \"%s\"", *icsFlag)
+		games, err = readFile(*icsFlag, readICS)
+	default:
+		if flag.NArg() < 1 {
+			return nil, fmt.Errorf("schedule to parse must be passed as an argument")
+		}
+		scheduleFile := flag.Arg(0)
+		log.Printf("parsing schedule file \"%s\"", scheduleFile)
+
+		yf, err := ioutil.ReadFile(scheduleFile)
+		if err != nil {
+			return nil, err
+		}
+		var schedule YamlSchedule
+		err = yaml.Unmarshal(yf, &schedule)
+		if err != nil {
+			return nil, err
+		}
+		return schedule, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	scheduleFile := os.Args[1]
-	log.Printf("parsing schedule file \"%s\"", scheduleFile)
 
-	yf, err := ioutil.ReadFile(scheduleFile)
+	seasonStart, err := start()
 	if err != nil {
-		log.Fatalln(err)
-		os.Exit(1)
+		return nil, err
 	}
-	var schedule YamlSchedule
-	err = yaml.Unmarshal(yf, &schedule)
+	log.Printf("computing weeks from season start %s", seasonStart.Format(dateFormat))
+	var venues *bts.VenueRegistry
+	if *venuesFlag != "" {
+		venues, err = bts.MakeVenueRegistry(*venuesFlag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s, err := buildSchedule(games, seasonStart, venues)
 	if err != nil {
-		log.Fatalln(err)
-		os.Exit(1)
+		return nil, err
 	}
-	log.Printf("read schedule: %v", schedule)
+	return makeYamlSchedule(s), nil
+}
 
-	ctx := context.Background()
-	fsclient, err = firestore.NewClient(ctx, projectID)
+// readFile opens a file and reads games from it with the given reader.
+func readFile(fileName string, read func(io.Reader) ([]importedGame, error)) ([]importedGame, error) {
+	f, err := os.Open(fileName)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
+	}
+	defer f.Close()
+	return read(f)
+}
+
+// exportSchedule writes the schedule in the given format.
+func exportSchedule(w io.Writer, format string, schedule YamlSchedule) error {
+	s := bts.ScheduleFromWeeks(schedule)
+	switch format {
+	case "yaml":
+		return s.WriteYaml(w)
+	case "csv":
+		return s.WriteCSV(w)
+	case "markdown":
+		return s.WriteMarkdown(w)
+	case "html":
+		return s.WriteHTML(w)
+	default:
+		return fmt.Errorf("unknown format \"%s\"", format)
+	}
+}
+
+// writeSchedule writes the schedule in the given format to a file or, if the file name is "-", to standard output.
+func writeSchedule(fileName string, format string, schedule YamlSchedule) error {
+	if fileName == "-" {
+		return exportSchedule(os.Stdout, format, schedule)
+	}
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := exportSchedule(f, format, schedule); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+	if *outFlag != "" && (*dryRunFlag || *updateFlag) {
+		log.Fatalln("-out cannot be combined with -dry-run or -update")
+		os.Exit(1)
+	}
+	if *storedFlag && *outFlag == "" {
+		log.Fatalln("-stored requires -out")
 		os.Exit(1)
 	}
-	loadTeams(ctx)
-	byeWeekTeam = fsclient.Collection("teams").Doc("bye week")
 
-	seasonRef, err := mostRecentSeason(ctx)
+	var seasonRef *firestore.DocumentRef
+	var seasonStart time.Time
+	if *outFlag == "" || *storedFlag {
+		var err error
+		fsclient, err = firestore.NewClient(ctx, projectID)
+		if err != nil {
+			log.Fatalln(err)
+			os.Exit(1)
+		}
+		loadTeams(ctx)
+		byeWeekTeam = fsclient.Collection("teams").Doc("bye week")
+
+		seasonRef, seasonStart, err = mostRecentSeason(ctx)
+		if err != nil {
+			log.Fatalln(err)
+			os.Exit(1)
+		}
+	}
+
+	var schedule YamlSchedule
+	var err error
+	if *storedFlag {
+		var stored []Schedule
+		stored, err = loadLatestSchedule(ctx, seasonRef)
+		schedule = scheduleWeeks(stored)
+	} else {
+		schedule, err = readSchedule(func() (time.Time, error) {
+			if *startFlag != "" {
+				return time.Parse(dateFormat, *startFlag)
+			}
+			if seasonRef == nil {
+				return time.Time{}, fmt.Errorf("-start is required to import a schedule without uploading it")
+			}
+			return seasonStart, nil
+		})
+	}
 	if err != nil {
 		log.Fatalln(err)
 		os.Exit(1)
 	}
+	log.Printf("read schedule: %v", schedule)
+
+	if *outFlag != "" {
+		if err := writeSchedule(*outFlag, *formatFlag, schedule); err != nil {
+			log.Fatalln(err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	schedules := make([]Schedule, 0)
 	teamErrors := 0
@@ -156,24 +317,56 @@ func main() {
 			// keep going -- will crash out eventually
 		}
 
-		opps := make([]*firestore.DocumentRef, len(opponents))
-		locs := make([]int, len(opponents))
-		for i, opp := range opponents {
-			if opp == "" {
-				opps[i] = byeWeekTeam
-				locs[i] = bts.Neutral
+		opps := make([]*firestore.DocumentRef, 0, len(opponents))
+		locs := make([]int, 0, len(opponents))
+		weeks := make([]int, 0, len(opponents))
+		kickoffs := make([]time.Time, 0, len(opponents))
+		venues := make([]string, 0, len(opponents))
+		statuses := make([]string, 0, len(opponents))
+		travel := make([]float64, 0, len(opponents))
+		oppTravel := make([]float64, 0, len(opponents))
+		hasWeeks, hasKickoffs, hasVenues, hasStatuses, hasTravel := false, false, false, false, false
+		for week, games := range opponents {
+			if len(games) == 0 {
+				opps = append(opps, byeWeekTeam)
+				locs = append(locs, bts.Neutral)
+				weeks = append(weeks, week)
+				kickoffs = append(kickoffs, time.Time{})
+				venues = append(venues, "")
+				statuses = append(statuses, bts.Scheduled.String())
+				travel = append(travel, -1)
+				oppTravel = append(oppTravel, -1)
 				continue
 			}
+			hasWeeks = hasWeeks || len(games) > 1
 
-			loc, other := splitLocTeam(opp)
-			team2, exists := otherTeams[other]
-			if !exists {
-				log.Fatalf(`team "%s" not found in teams`, other)
-				teamErrors++
-				continue
+			for _, opp := range games {
+				hasKickoffs = hasKickoffs || !opp.Kickoff.IsZero()
+				hasVenues = hasVenues || opp.Venue != ""
+				hasStatuses = hasStatuses || opp.Status != bts.Scheduled
+				hasTravel = hasTravel || opp.Travel != nil
+
+				loc, other := splitLocTeam(opp.Opponent)
+				team2, exists := otherTeams[other]
+				if !exists {
+					log.Fatalf(`team "%s" not found in teams`, other)
+					teamErrors++
+					continue
+				}
+				opps = append(opps, team2)
+				locs = append(locs, int(loc))
+				weeks = append(weeks, week)
+				kickoffs = append(kickoffs, opp.Kickoff)
+				venues = append(venues, opp.Venue)
+				statuses = append(statuses, opp.Status.String())
+				if opp.Travel != nil {
+					travel = append(travel, opp.Travel[0])
+					oppTravel = append(oppTravel, opp.Travel[1])
+				} else {
+					travel = append(travel, -1)
+					oppTravel = append(oppTravel, -1)
+				}
 			}
-			opps[i] = team2
-			locs[i] = int(loc)
 		}
 
 		s := Schedule{
@@ -181,6 +374,22 @@ func main() {
 			Opponents: opps,
 			Locales:   locs,
 		}
+		if hasWeeks {
+			s.Weeks = weeks
+		}
+		if hasKickoffs {
+			s.Kickoffs = kickoffs
+		}
+		if hasVenues {
+			s.Venues = venues
+		}
+		if hasStatuses {
+			s.Statuses = statuses
+		}
+		if hasTravel {
+			s.Travel = travel
+			s.OpponentTravel = oppTravel
+		}
 		log.Printf("parsed schedule: %v", s)
 		schedules = append(schedules, s)
 	}
@@ -189,29 +398,18 @@ func main() {
 		os.Exit(2)
 	}
 
-	// Write in transaction
-	seasonSchedRef := fsclient.Collection("schedules").NewDoc()
-	teamSchedCol := seasonSchedRef.Collection("teams")
-	seasonSched := SeasonSchedule{
-		Season: seasonRef,
-	}
-	err = fsclient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		err := tx.Create(seasonSchedRef, &seasonSched)
+	if *dryRunFlag {
+		stored, err := loadLatestSchedule(ctx, seasonRef)
 		if err != nil {
-			return err
+			log.Fatalln(err)
+			os.Exit(3)
 		}
+		diffs := printDiff(os.Stdout, scheduleWeeks(stored), scheduleWeeks(schedules))
+		log.Printf("dry run: %d differences from the stored schedule", diffs)
+		return
+	}
 
-		for _, schedule := range schedules {
-			dr := teamSchedCol.NewDoc()
-			err := tx.Create(dr, &schedule)
-			if err != nil {
-				return err
-			}
-		}
-
-		return nil
-	})
-
+	err = writeSchedules(ctx, seasonRef, schedules, *updateFlag)
 	if err != nil {
 		log.Fatalln(err)
 		os.Exit(3)
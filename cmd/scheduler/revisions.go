@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"google.golang.org/api/iterator"
+)
+
+// latestScheduleQuery selects the most recent schedule stored for a season.
+// Schedules are ordered by timestamp so readers always see the newest revision.
+// The query needs the composite index on season and descending timestamp declared in firestore.indexes.json.
+func latestScheduleQuery(seasonRef *firestore.DocumentRef) firestore.Query {
+	return fsclient.Collection("schedules").Where("season", "==", seasonRef).OrderBy("timestamp", firestore.Desc).Limit(1)
+}
+
+// anyScheduleQuery selects any schedule stored for a season.
+// Schedules stored without a timestamp are left out of latestScheduleQuery, but are found by this query.
+func anyScheduleQuery(seasonRef *firestore.DocumentRef) firestore.Query {
+	return fsclient.Collection("schedules").Where("season", "==", seasonRef).Limit(1)
+}
+
+// nextVersion is the version of the revision after the given schedule. Schedules stored before revisions were versioned count as version 1.
+func nextVersion(latest SeasonSchedule) int {
+	if latest.Version < 1 {
+		return 2
+	}
+	return latest.Version + 1
+}
+
+// readLatestSchedule reads the most recent schedule stored for a season and the team schedules within it.
+// Queries are run with documents, so the same reads can be made inside or outside of a transaction.
+// If no schedule with a timestamp has been stored for the season, it falls back to any schedule stored for the season,
+// and if there is none, it returns a nil snapshot.
+func readLatestSchedule(seasonRef *firestore.DocumentRef, documents func(firestore.Query) *firestore.DocumentIterator) (*firestore.DocumentSnapshot, []*firestore.DocumentSnapshot, error) {
+	schedDoc, err := firstDocument(documents(latestScheduleQuery(seasonRef)))
+	if err == iterator.Done {
+		schedDoc, err = firstDocument(documents(anyScheduleQuery(seasonRef)))
+	}
+	if err == iterator.Done {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	teamDocs, err := documents(schedDoc.Ref.Collection("teams").Query).GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	return schedDoc, teamDocs, nil
+}
+
+// firstDocument reads the first document from an iterator and stops it.
+func firstDocument(itr *firestore.DocumentIterator) (*firestore.DocumentSnapshot, error) {
+	defer itr.Stop()
+	return itr.Next()
+}
+
+// loadLatestSchedule loads the team schedules of the most recent schedule stored for a season.
+func loadLatestSchedule(ctx context.Context, seasonRef *firestore.DocumentRef) ([]Schedule, error) {
+	schedDoc, teamDocs, err := readLatestSchedule(seasonRef, func(q firestore.Query) *firestore.DocumentIterator {
+		return q.Documents(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if schedDoc == nil {
+		log.Printf("no schedule stored for season \"%s\"", seasonRef.ID)
+		return nil, nil
+	}
+
+	var ss SeasonSchedule
+	if err := schedDoc.DataTo(&ss); err != nil {
+		return nil, err
+	}
+	log.Printf("latest stored schedule: \"%s\" (version %d, %s)", schedDoc.Ref.ID, ss.Version, ss.Timestamp)
+
+	schedules := make([]Schedule, len(teamDocs))
+	for i, doc := range teamDocs {
+		if err := doc.DataTo(&schedules[i]); err != nil {
+			return nil, err
+		}
+	}
+	return schedules, nil
+}
+
+// writeSchedules stores team schedules for a season in a transaction.
+// When update is true, the most recent schedule for the season is revised in place: its team schedules are replaced and its version is incremented.
+// Otherwise, or if no schedule has been stored for the season, a new schedule document is created.
+// Either way, the timestamp of the schedule is set to the time of the write.
+func writeSchedules(ctx context.Context, seasonRef *firestore.DocumentRef, schedules []Schedule, update bool) error {
+	return fsclient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		schedDoc, teamDocs, err := readLatestSchedule(seasonRef, func(q firestore.Query) *firestore.DocumentIterator {
+			return tx.Documents(q)
+		})
+		if err != nil {
+			return err
+		}
+
+		seasonSched := SeasonSchedule{
+			Season:  seasonRef,
+			Version: 1,
+		}
+		if schedDoc != nil {
+			var latest SeasonSchedule
+			if err := schedDoc.DataTo(&latest); err != nil {
+				return err
+			}
+			seasonSched.Version = nextVersion(latest)
+		}
+
+		var seasonSchedRef *firestore.DocumentRef
+		if update && schedDoc != nil {
+			seasonSchedRef = schedDoc.Ref
+			log.Printf("updating schedule \"%s\" to version %d", seasonSchedRef.ID, seasonSched.Version)
+			for _, doc := range teamDocs {
+				if err := tx.Delete(doc.Ref); err != nil {
+					return err
+				}
+			}
+			if err := tx.Set(seasonSchedRef, &seasonSched); err != nil {
+				return err
+			}
+		} else {
+			seasonSchedRef = fsclient.Collection("schedules").NewDoc()
+			log.Printf("creating schedule \"%s\" version %d", seasonSchedRef.ID, seasonSched.Version)
+			if err := tx.Create(seasonSchedRef, &seasonSched); err != nil {
+				return err
+			}
+		}
+
+		teamSchedCol := seasonSchedRef.Collection("teams")
+		for _, schedule := range schedules {
+			dr := teamSchedCol.NewDoc()
+			err := tx.Create(dr, &schedule)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// scheduleWeeks converts team schedules into the YAML notation keyed by team name, with empty weeks for byes.
+func scheduleWeeks(schedules []Schedule) YamlSchedule {
+	weeks := make(YamlSchedule)
+	for _, s := range schedules {
+		nWeeks := len(s.Opponents)
+		if len(s.Weeks) > 0 {
+			nWeeks = 0
+			for _, week := range s.Weeks {
+				if week >= nWeeks {
+					nWeeks = week + 1
+				}
+			}
+		}
+		teamWeeks := make([]bts.ScheduleWeek, nWeeks)
+		for i := range teamWeeks {
+			teamWeeks[i] = make(bts.ScheduleWeek, 0, 1)
+		}
+		for i, opp := range s.Opponents {
+			if opp == nil || opp.ID == byeWeekTeam.ID {
+				continue
+			}
+			week := i
+			if i < len(s.Weeks) {
+				week = s.Weeks[i]
+			}
+			var entry bts.ScheduleEntry
+			if i < len(s.Kickoffs) {
+				entry.Kickoff = s.Kickoffs[i]
+			}
+			if i < len(s.Venues) {
+				entry.Venue = s.Venues[i]
+			}
+			if i < len(s.Statuses) {
+				status, err := bts.ParseGameStatus(s.Statuses[i])
+				if err != nil {
+					log.Printf("%s week %d: %v", teamName(s.Team), week, err)
+				}
+				entry.Status = status
+			}
+			if i < len(s.Travel) && i < len(s.OpponentTravel) && s.Travel[i] >= 0 && s.OpponentTravel[i] >= 0 {
+				entry.Travel = []float64{s.Travel[i], s.OpponentTravel[i]}
+			}
+			loc := bts.RelativeLocation(bts.Neutral)
+			if i < len(s.Locales) {
+				loc = bts.RelativeLocation(s.Locales[i])
+			}
+			entry.Opponent = bts.LocationPrefix(loc) + teamName(opp)
+			teamWeeks[week] = append(teamWeeks[week], entry)
+		}
+		weeks[teamName(s.Team)] = teamWeeks
+	}
+	return weeks
+}
+
+// printDiff writes the weeks that differ between the stored and proposed schedules of every team, and returns the number of differences.
+// Weeks differ if their games, locations, kickoff times, venues, or statuses differ.
+func printDiff(w io.Writer, stored, proposed YamlSchedule) int {
+	teams := make([]string, 0, len(stored)+len(proposed))
+	for team := range stored {
+		teams = append(teams, team)
+	}
+	for team := range proposed {
+		if _, ok := stored[team]; !ok {
+			teams = append(teams, team)
+		}
+	}
+	sort.Strings(teams)
+
+	show := func(weeks []bts.ScheduleWeek, week int) string {
+		if week >= len(weeks) {
+			return "(none)"
+		}
+		return weeks[week].String()
+	}
+	showAll := func(weeks []bts.ScheduleWeek) string {
+		shown := make([]string, len(weeks))
+		for i, wk := range weeks {
+			shown[i] = wk.String()
+		}
+		return strings.Join(shown, ", ")
+	}
+
+	diffs := 0
+	for _, team := range teams {
+		old, inStored := stored[team]
+		revised, inProposed := proposed[team]
+		switch {
+		case !inStored:
+			fmt.Fprintf(w, "%s: added [%s]\n", team, showAll(revised))
+			diffs++
+			continue
+		case !inProposed:
+			fmt.Fprintf(w, "%s: removed [%s]\n", team, showAll(old))
+			diffs++
+			continue
+		}
+
+		nWeeks := len(old)
+		if len(revised) > nWeeks {
+			nWeeks = len(revised)
+		}
+		for week := 0; week < nWeeks; week++ {
+			before, after := show(old, week), show(revised, week)
+			if before != after {
+				fmt.Fprintf(w, "%s week %d: %s -> %s\n", team, week, before, after)
+				diffs++
+			}
+		}
+	}
+	return diffs
+}
+
+// teamName gets the name of a team from its DocumentRef, falling back to the document ID for unknown teams.
+func teamName(ref *firestore.DocumentRef) string {
+	if name, ok := teamNames[ref.ID]; ok && name != "" {
+		return name
+	}
+	return ref.ID
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+)
+
+var testStart = time.Date(2020, time.August, 31, 0, 0, 0, 0, time.UTC)
+
+// notation strips the kickoff times and venues from a schedule.
+func notation(ys YamlSchedule) map[string][]string {
+	out := make(map[string][]string)
+	for team, weeks := range ys {
+		out[team] = make([]string, len(weeks))
+		for i, w := range weeks {
+			out[team][i] = w.Notation()
+		}
+	}
+	return out
+}
+
+// fromNotation makes a schedule without kickoff times or venues.
+func fromNotation(m map[string][]string) YamlSchedule {
+	ys := make(YamlSchedule)
+	for team, weeks := range m {
+		ys[team] = make([]bts.ScheduleWeek, len(weeks))
+		for i, w := range weeks {
+			ys[team][i] = bts.ParseWeekNotation(w)
+		}
+	}
+	return ys
+}
+
+func TestImportCSV(t *testing.T) {
+	in := `date,home,away,neutral
+2020-09-05,AAA,BBB,
+2020-09-12,CCC,AAA,false
+2020-09-26,AAA,CCC,true
+`
+	games, err := readCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := buildSchedule(games, testStart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"AAA": {"BBB", "@CCC", "", "!CCC"},
+		"BBB": {"@AAA", "", "", ""},
+		"CCC": {"", "AAA", "", "!AAA"},
+	}
+	ys := makeYamlSchedule(s)
+	if n := notation(ys); !reflect.DeepEqual(n, expected) {
+		t.Errorf("expected %v, got %v", expected, n)
+	}
+	if k := ys["CCC"][1][0].Kickoff; !k.Equal(time.Date(2020, time.September, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected kickoff on 2020-09-12, got %s", k)
+	}
+
+	if _, err := readCSV(strings.NewReader("date,home\n2020-09-05,AAA\n")); err == nil {
+		t.Errorf("expected error for missing away column")
+	}
+}
+
+func TestImportICS(t *testing.T) {
+	in := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20200905\r\nSUMMARY:BBB at AAA\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;TZID=America/Chicago:20200912T110000\r\nSUMMARY:AAA @\r\n  CCC\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20200912T160000Z\r\nSUMMARY:AAA @ CCC\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20200926T160000Z\r\nSUMMARY:AAA vs. CCC\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	games, err := readICS(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 4 {
+		t.Fatalf("expected 4 games, got %d", len(games))
+	}
+	s, err := buildSchedule(games, testStart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"AAA": {"BBB", "@CCC", "", "!CCC"},
+		"BBB": {"@AAA", "", "", ""},
+		"CCC": {"", "AAA", "", "!AAA"},
+	}
+	ys := makeYamlSchedule(s)
+	if n := notation(ys); !reflect.DeepEqual(n, expected) {
+		t.Errorf("expected %v, got %v", expected, n)
+	}
+	if k := ys["AAA"][1][0].Kickoff; !k.Equal(time.Date(2020, time.September, 12, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected kickoff at 2020-09-12T16:00:00Z, got %s", k)
+	}
+}
+
+func TestWeekOf(t *testing.T) {
+	central := time.FixedZone("CDT", -5*60*60)
+	tests := []struct {
+		kickoff  time.Time
+		expected int
+	}{
+		{time.Date(2020, time.August, 31, 0, 0, 0, 0, time.UTC), 0},
+		// A late Sunday kickoff is Monday in UTC, but still in the first week.
+		{time.Date(2020, time.September, 6, 21, 30, 0, 0, central), 0},
+		{time.Date(2020, time.September, 7, 12, 0, 0, 0, central), 1},
+	}
+	for _, test := range tests {
+		if w := weekOf(test.kickoff, testStart); w != test.expected {
+			t.Errorf("kickoff %s: expected week %d, got %d", test.kickoff, test.expected, w)
+		}
+	}
+}
+
+func TestBuildSchedule(t *testing.T) {
+	day := func(d int) time.Time { return testStart.AddDate(0, 0, d) }
+	if _, err := buildSchedule([]importedGame{{kickoff: day(-1), home: "AAA", away: "BBB"}}, testStart, nil); err == nil {
+		t.Errorf("expected error for game before the season starts")
+	}
+
+	// AAA plays twice in week 0, and the repeated game is counted once.
+	twice := []importedGame{{kickoff: day(1), home: "AAA", away: "BBB"}, {kickoff: day(3), home: "CCC", away: "AAA"}, {kickoff: day(3), home: "CCC", away: "AAA"}}
+	s, err := buildSchedule(twice, testStart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"AAA": {"BBB+@CCC"},
+		"BBB": {"@AAA"},
+		"CCC": {"AAA"},
+	}
+	if n := notation(makeYamlSchedule(s)); !reflect.DeepEqual(n, expected) {
+		t.Errorf("expected %v, got %v", expected, n)
+	}
+
+	// Neutral-site games listed in either order are the same game.
+	neutral := []importedGame{{kickoff: day(1), home: "AAA", away: "BBB", neutral: true}, {kickoff: day(1), home: "BBB", away: "AAA", neutral: true}}
+	s, err = buildSchedule(neutral, testStart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string][]string{
+		"AAA": {"!BBB"},
+		"BBB": {"!AAA"},
+	}
+	if n := notation(makeYamlSchedule(s)); !reflect.DeepEqual(n, expected) {
+		t.Errorf("expected %v, got %v", expected, n)
+	}
+}
+
+func TestExportSchedule(t *testing.T) {
+	var b strings.Builder
+	if err := exportSchedule(&b, "yaml", fromNotation(map[string][]string{"BBB": {"@AAA", ""}, "AAA": {"BBB", ""}})); err != nil {
+		t.Fatal(err)
+	}
+	expected := "AAA: [\"BBB\",  \"\"]\nBBB: [\"@AAA\", \"\"]\n"
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+	if err := exportSchedule(&b, "pdf", YamlSchedule{}); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	stored := fromNotation(map[string][]string{
+		"AAA": {"BBB", "", "@CCC"},
+		"BBB": {"@AAA", "", ""},
+		"DDD": {"", "", ""},
+	})
+	proposed := fromNotation(map[string][]string{
+		"AAA": {"BBB", "!CCC", ""},
+		"BBB": {"@AAA", "", "", "CCC"},
+		"CCC": {"", "!AAA", "", "@BBB"},
+	})
+	proposed["BBB"][0][0].Kickoff = time.Date(2020, time.September, 5, 19, 0, 0, 0, time.UTC)
+	proposed["AAA"][0][0].Status = bts.Cancelled
+	var b strings.Builder
+	diffs := printDiff(&b, stored, proposed)
+	expected := `AAA week 0: BBB -> BBB (cancelled)
+AAA week 1: BYE -> !CCC
+AAA week 2: @CCC -> BYE
+BBB week 0: @AAA -> @AAA (2020-09-05T19:00:00Z)
+BBB week 3: (none) -> CCC
+CCC: added [BYE, !AAA, BYE, @BBB]
+DDD: removed [BYE, BYE, BYE]
+`
+	if diffs != 7 || b.String() != expected {
+		t.Errorf("expected 7 differences\n%s\ngot %d\n%s", expected, diffs, b.String())
+	}
+}
+
+func TestImportVenues(t *testing.T) {
+	in := `date,home,away,neutral,venue
+2020-09-05,AAA,BBB,true,Soldier Field
+2020-09-12,CCC,AAA,,
+`
+	games, err := readCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	venues := &bts.VenueRegistry{
+		Teams: map[bts.Team]bts.Venue{
+			{Name4: "AAA"}: {Name: "AAA Stadium", Latitude: 40.0992, Longitude: -88.2360},
+			{Name4: "BBB"}: {Name: "BBB Field", Latitude: 42.0654, Longitude: -87.6925},
+			{Name4: "CCC"}: {Name: "CCC Stadium", Latitude: 40.4352, Longitude: -86.9186},
+		},
+		Venues: map[string]bts.Venue{"Soldier Field": {Name: "Soldier Field", Latitude: 41.8623, Longitude: -87.6167}},
+	}
+	s, err := buildSchedule(games, testStart, venues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"AAA": {">BBB", "@CCC"},
+		"BBB": {"<AAA", ""},
+		"CCC": {"", "AAA"},
+	}
+	ys := makeYamlSchedule(s)
+	if n := notation(ys); !reflect.DeepEqual(n, expected) {
+		t.Errorf("expected %v, got %v", expected, n)
+	}
+	if v := ys["BBB"][0][0].Venue; v != "Soldier Field" {
+		t.Errorf("expected venue Soldier Field, got %s", v)
+	}
+	if tr := ys["BBB"][0][0].Travel; len(tr) != 2 || tr[0] >= tr[1] {
+		t.Errorf("expected BBB to travel less than AAA, got %v", tr)
+	}
+	if tr := ys["CCC"][1][0].Travel; tr != nil {
+		t.Errorf("expected no travel for game without venue, got %v", tr)
+	}
+
+	games[0].venue = "Nowhere"
+	if _, err := buildSchedule(games, testStart, venues); err == nil {
+		t.Errorf("expected error for unknown venue")
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	for _, tc := range []struct{ latest, next int }{{0, 2}, {1, 2}, {4, 5}} {
+		if v := nextVersion(SeasonSchedule{Version: tc.latest}); v != tc.next {
+			t.Errorf("version %d: expected next version %d, got %d", tc.latest, tc.next, v)
+		}
+	}
+}
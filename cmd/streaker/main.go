@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
 	"google.golang.org/api/iterator"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -21,21 +22,34 @@ var projectID = os.Getenv("GCP_PROJECT")
 // otherTeams is a mapping of other team names to team DocumentRefs in Firestore.
 var otherTeams = make(map[string]*firestore.DocumentRef)
 
-// lukeNames is a mapping of pickers to names Luke has given them.
+// teamNames is a mapping of team DocumentRef IDs to team names as used in schedules.
+var teamNames = make(map[string]string)
+
+// lukeNames is a mapping of the names and aliases Luke has given pickers to picker DocumentRefs in Firestore.
 var lukeNames = make(map[string]*firestore.DocumentRef)
 
+// pickers is a mapping of picker DocumentRef IDs to pickers.
+var pickers = make(map[string]Picker)
+
 var remainingYaml = flag.String("remaining", "", "Picker team remaining YAML file.")
 var typesYaml = flag.String("types", "", "Picker picks remaining YAML file.")
 var weekNumber = flag.Int("week", -1, "Week of picks (starting at 0 for preseason).")
+var constraintsYaml = flag.String("constraints", "", "Picker pick constraints YAML file (optional).")
+var rulesFlag = flag.String("rules", "", "Pool rules for scoring picks on games that are not played as scheduled, as comma-separated status=rule pairs, where status is postponed, cancelled, or no-contest, and rule is refund, win, or loss (e.g., \"cancelled=win,no-contest=loss\"). Unlisted statuses are refunded. Pickers are validated with the rules, and the rules are passed on to bts-mc.")
+var topicFlag = flag.String("topic", "", "Pub/Sub topic to which a bts-mc request is published for every picker after the upload (optional).")
+var localFlag = flag.Bool("local", false, "Write the bts-mc requests to standard output instead of publishing them to a Pub/Sub topic.")
 
 // Team represents how teams are stored in Firestore
 type Team struct {
+	Name4      string   `firestore:"name_4"`
 	OtherNames []string `firestore:"other_names"`
 }
 
 // Picker represents how pickers are stored in Firestore
 type Picker struct {
-	NameLuke string `firestore:"name_luke"`
+	NameLuke string   `firestore:"name_luke"`
+	Aliases  []string `firestore:"aliases,omitempty"`
+	Inactive bool     `firestore:"inactive,omitempty"`
 }
 
 // loadTeams loads the team maps, defined above.
@@ -58,6 +72,8 @@ func loadTeams(ctx context.Context) {
 			panic(err)
 		}
 
+		teamNames[teamDoc.Ref.ID] = team.Name4
+
 		// store by other name
 		for _, name := range team.OtherNames {
 			if _, exists := otherTeams[name]; exists {
@@ -90,14 +106,18 @@ func loadPickers(ctx context.Context) {
 			panic(err)
 		}
 
-		// store by other name
-		name := picker.NameLuke
-		if _, exists := lukeNames[name]; exists {
-			err = fmt.Errorf("loadPickers: luke name \"%s\" is ambiguous: %v", name, picker)
-			panic(err)
+		pickers[pickerDoc.Ref.ID] = picker
+
+		// store by luke name and aliases
+		names := append([]string{picker.NameLuke}, picker.Aliases...)
+		for _, name := range names {
+			if _, exists := lukeNames[name]; exists {
+				err = fmt.Errorf("loadPickers: luke name \"%s\" is ambiguous: %v", name, picker)
+				panic(err)
+			}
+			lukeNames[name] = pickerDoc.Ref
+			log.Printf("loaded picker luke name: %s -> %s", name, pickerDoc.Ref.ID)
 		}
-		lukeNames[name] = pickerDoc.Ref
-		log.Printf("loaded picker luke name: %s -> %s", name, pickerDoc.Ref.ID)
 	}
 }
 
@@ -131,6 +151,81 @@ type Streak struct {
 	Picker             *firestore.DocumentRef   `firestore:"picker"`
 	Remaining          []*firestore.DocumentRef `firestore:"remaining"`
 	PickTypesRemaining []int                    `firestore:"pick_types_remaining"`
+	Constraints        []Constraint             `firestore:"constraints"`
+}
+
+// Constraint is how a constraint on the weeks a picker may pick a team is stored.
+// See bts.PickConstraint for the meaning of the fields.
+type Constraint struct {
+	Must     *firestore.DocumentRef `firestore:"must"`
+	Never    *firestore.DocumentRef `firestore:"never"`
+	Week     *int                   `firestore:"week"`
+	After    *int                   `firestore:"after"`
+	Before   *int                   `firestore:"before"`
+	Opponent *firestore.DocumentRef `firestore:"opponent"`
+}
+
+// teamRef looks up a team by other name, allowing empty names.
+func teamRef(name string) (*firestore.DocumentRef, error) {
+	if name == "" {
+		return nil, nil
+	}
+	ref, exists := otherTeams[name]
+	if !exists {
+		return nil, fmt.Errorf("team name \"%s\" not in teams", name)
+	}
+	return ref, nil
+}
+
+// parseConstraints converts pick constraints keyed by picker name into constraints that refer to Firestore documents.
+func parseConstraints(cm bts.ConstraintMap) (map[*firestore.DocumentRef][]Constraint, error) {
+	out := make(map[*firestore.DocumentRef][]Constraint)
+	for pickerName, constraints := range cm {
+		userRef, exists := lukeNames[pickerName]
+		if !exists {
+			return nil, fmt.Errorf("luke name \"%s\" not in pickers", pickerName)
+		}
+		parsed := make([]Constraint, len(constraints))
+		for i, c := range constraints {
+			must, err := teamRef(c.Must)
+			if err != nil {
+				return nil, err
+			}
+			never, err := teamRef(c.Never)
+			if err != nil {
+				return nil, err
+			}
+			opponent, err := teamRef(c.Opponent)
+			if err != nil {
+				return nil, err
+			}
+			parsed[i] = Constraint{Must: must, Never: never, Week: c.Week, After: c.After, Before: c.Before, Opponent: opponent}
+		}
+		out[userRef] = parsed
+	}
+	return out, nil
+}
+
+// scheduleName converts the other name of a team to the name used in schedules, or returns the name unchanged if the team is unknown.
+func scheduleName(name string) string {
+	if ref, exists := otherTeams[name]; exists {
+		return teamNames[ref.ID]
+	}
+	return name
+}
+
+// scheduleConstraint converts a pick constraint to refer to teams by the names used in schedules.
+func scheduleConstraint(c bts.PickConstraint) bts.PickConstraint {
+	if c.Must != "" {
+		c.Must = scheduleName(c.Must)
+	}
+	if c.Never != "" {
+		c.Never = scheduleName(c.Never)
+	}
+	if c.Opponent != "" {
+		c.Opponent = scheduleName(c.Opponent)
+	}
+	return c
 }
 
 func main() {
@@ -143,11 +238,26 @@ func main() {
 		os.Exit(-1)
 	}
 
+	flag.Usage = usage
 	flag.Parse()
+	if flag.NArg() > 0 {
+		loadPickers(ctx)
+		err = runCommand(ctx, flag.Args())
+		if err != nil {
+			log.Fatalln(err)
+			os.Exit(1)
+		}
+		return
+	}
 	if *weekNumber < 0 {
 		log.Fatalf("invalid week number %d", *weekNumber)
 		os.Exit(1)
 	}
+	rules, err := bts.ParsePoolRules(*rulesFlag)
+	if err != nil {
+		log.Fatalln(err)
+		os.Exit(1)
+	}
 
 	loadTeams(ctx)
 	loadPickers(ctx)
@@ -157,6 +267,9 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Problems are collected so that all of them can be reported before anything is written.
+	errs := make([]error, 0)
+
 	parsedRemaining := make(map[*firestore.DocumentRef][]*firestore.DocumentRef)
 	yr, err := ioutil.ReadFile(*remainingYaml)
 	if err != nil {
@@ -171,16 +284,20 @@ func main() {
 	for userName, remainingTeams := range rem {
 		userRef, exists := lukeNames[userName]
 		if !exists {
-			log.Fatalf("luke name \"%s\" not in pickers", userName)
-			os.Exit(2)
+			errs = append(errs, fmt.Errorf("luke name \"%s\" not in pickers", userName))
+			continue
+		}
+		if pickers[userRef.ID].Inactive {
+			errs = append(errs, fmt.Errorf("picker \"%s\" is deactivated", userName))
+			continue
 		}
 
 		teamsRemaining := make([]*firestore.DocumentRef, len(remainingTeams))
 		for i, team := range remainingTeams {
 			teamRef, exists := otherTeams[team]
 			if !exists {
-				log.Fatalf("team name \"%s\" not in teams", team)
-				os.Exit(3)
+				errs = append(errs, fmt.Errorf("picker \"%s\": team name \"%s\" not in teams", userName, team))
+				continue
 			}
 			teamsRemaining[i] = teamRef
 		}
@@ -213,14 +330,73 @@ func main() {
 		for pickerName, typesRemaining := range typ {
 			userRef, exists := lukeNames[pickerName]
 			if !exists {
-				log.Fatalf("luke name \"%s\" not in pickers", pickerName)
-				os.Exit(2)
+				errs = append(errs, fmt.Errorf("luke name \"%s\" not in pickers", pickerName))
+				continue
 			}
 
 			parsedTypes[userRef] = typesRemaining
 		}
 	}
 
+	parsedConstraints := make(map[*firestore.DocumentRef][]Constraint)
+	cm := make(bts.ConstraintMap)
+	if *constraintsYaml != "" {
+		cm, err = bts.MakeConstraints(*constraintsYaml)
+		if err != nil {
+			log.Fatalf("error reading constraints YAML file \"%s\": %v", *constraintsYaml, err)
+			os.Exit(1)
+		}
+		parsedConstraints, err = parseConstraints(cm)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Check every picker as bts-mc will see them.
+	schedule, err := loadSchedule(ctx, seasonRef)
+	if err != nil {
+		log.Fatalln(err)
+		os.Exit(1)
+	}
+	if *weekNumber > schedule.NumWeeks() {
+		log.Fatalf("week %d is after the end of the %d-week schedule", *weekNumber, schedule.NumWeeks())
+		os.Exit(1)
+	}
+	schedule.FilterWeeks(*weekNumber)
+	for userName, remainingTeams := range rem {
+		userRef, exists := lukeNames[userName]
+		if !exists {
+			continue
+		}
+		typesRem, ok := parsedTypes[userRef]
+		if !ok {
+			errs = append(errs, fmt.Errorf("picker \"%s\" does not have types remaining defined", userName))
+			continue
+		}
+		remaining := make(bts.Remaining, 0, len(remainingTeams))
+		for _, team := range remainingTeams {
+			if ref, exists := otherTeams[team]; exists {
+				remaining = append(remaining, bts.Team{Name4: teamNames[ref.ID]})
+			}
+		}
+		if len(remaining) != len(remainingTeams) {
+			// unknown teams are already reported
+			continue
+		}
+		constraints := make([]bts.PickConstraint, len(cm[userName]))
+		for i, c := range cm[userName] {
+			constraints[i] = scheduleConstraint(c)
+		}
+		errs = append(errs, validatePicker(ctx, userName, remaining, typesRem, constraints, rules, &schedule, *weekNumber)...)
+	}
+	if len(errs) > 0 {
+		for _, err := range errs {
+			log.Println(err)
+		}
+		log.Fatalf("detected %d errors: nothing written", len(errs))
+		os.Exit(2)
+	}
+
 	// Write everything in transaction
 	picksRef := fsclient.Collection("streak_teams_remaining").NewDoc()
 	picksDoc := Picks{
@@ -245,6 +421,7 @@ func main() {
 				Picker:             pickerRef,
 				Remaining:          remRefs,
 				PickTypesRemaining: typesRem,
+				Constraints:        parsedConstraints[pickerRef],
 			}
 			log.Printf("making picker %v", s)
 			err := tx.Create(remDoc, &s)
@@ -260,5 +437,29 @@ func main() {
 		os.Exit(4)
 	}
 
+	var pub publisher
+	switch {
+	case *localFlag:
+		pub = localPublisher{w: os.Stdout}
+	case *topicFlag != "":
+		pub, err = newPubsubPublisher(ctx, projectID, *topicFlag)
+		if err != nil {
+			log.Fatalln(err)
+			os.Exit(5)
+		}
+	}
+	if pub != nil {
+		names := make([]string, 0, len(rem))
+		for pickerName := range rem {
+			names = append(names, pickerName)
+		}
+		err = publishRequests(ctx, pub, names, *weekNumber, *rulesFlag)
+		if err != nil {
+			log.Fatalln(err)
+			os.Exit(5)
+		}
+		log.Printf("requested predictions for %d pickers", len(names))
+	}
+
 	log.Printf("DONE")
 }
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// commands are the picker registry subcommands, keyed by name.
+var commands = map[string]struct {
+	usage string
+	nargs int
+	run   func(ctx context.Context, args []string) error
+}{
+	"add":        {"add NAME", 1, addPicker},
+	"rename":     {"rename NAME NEWNAME", 2, renamePicker},
+	"alias":      {"alias NAME ALIAS", 2, aliasPicker},
+	"deactivate": {"deactivate NAME", 1, deactivatePicker},
+	"list":       {"list", 0, listPickers},
+}
+
+// usage prints how to upload picks and how to run the picker registry subcommands.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(out, "  %s -week WEEK -remaining FILE [-types FILE] [-constraints FILE] [-topic TOPIC | -local]\n", os.Args[0])
+	fmt.Fprintf(out, "\tUpload the teams and picks each picker has remaining.\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s %s\n", os.Args[0], commands[name].usage)
+	}
+	fmt.Fprintf(out, "\tManage the registry of pickers. Names and aliases are the names Luke gives pickers.\n")
+	flag.PrintDefaults()
+}
+
+// runCommand runs the picker registry subcommand given by the first argument.
+func runCommand(ctx context.Context, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command \"%s\"", args[0])
+	}
+	if len(args)-1 != cmd.nargs {
+		return fmt.Errorf("usage: %s", cmd.usage)
+	}
+	return cmd.run(ctx, args[1:])
+}
+
+// checkNewName checks that a name can be given to a picker without making any names ambiguous.
+func checkNewName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("picker name cannot be empty")
+	}
+	if ref, exists := lukeNames[name]; exists {
+		return fmt.Errorf("luke name \"%s\" already used by picker %s", name, ref.ID)
+	}
+	return nil
+}
+
+// lookupPicker looks up a picker by name or alias.
+func lookupPicker(name string) (*firestore.DocumentRef, error) {
+	ref, exists := lukeNames[name]
+	if !exists {
+		return nil, fmt.Errorf("luke name \"%s\" not in pickers", name)
+	}
+	return ref, nil
+}
+
+func addPicker(ctx context.Context, args []string) error {
+	name := args[0]
+	if err := checkNewName(name); err != nil {
+		return err
+	}
+	ref, _, err := fsclient.Collection("pickers").Add(ctx, Picker{NameLuke: name})
+	if err != nil {
+		return err
+	}
+	log.Printf("added picker %s: %s", ref.ID, name)
+	return nil
+}
+
+func renamePicker(ctx context.Context, args []string) error {
+	ref, err := lookupPicker(args[0])
+	if err != nil {
+		return err
+	}
+	if err := checkNewName(args[1]); err != nil {
+		return err
+	}
+	_, err = ref.Update(ctx, []firestore.Update{{Path: "name_luke", Value: args[1]}})
+	if err != nil {
+		return err
+	}
+	log.Printf("renamed picker %s: %s -> %s", ref.ID, pickers[ref.ID].NameLuke, args[1])
+	return nil
+}
+
+func aliasPicker(ctx context.Context, args []string) error {
+	ref, err := lookupPicker(args[0])
+	if err != nil {
+		return err
+	}
+	if err := checkNewName(args[1]); err != nil {
+		return err
+	}
+	_, err = ref.Update(ctx, []firestore.Update{{Path: "aliases", Value: firestore.ArrayUnion(args[1])}})
+	if err != nil {
+		return err
+	}
+	log.Printf("aliased picker %s: %s -> %s", ref.ID, args[1], pickers[ref.ID].NameLuke)
+	return nil
+}
+
+func deactivatePicker(ctx context.Context, args []string) error {
+	ref, err := lookupPicker(args[0])
+	if err != nil {
+		return err
+	}
+	if pickers[ref.ID].Inactive {
+		return fmt.Errorf("picker \"%s\" is already deactivated", args[0])
+	}
+	_, err = ref.Update(ctx, []firestore.Update{{Path: "inactive", Value: true}})
+	if err != nil {
+		return err
+	}
+	log.Printf("deactivated picker %s: %s", ref.ID, pickers[ref.ID].NameLuke)
+	return nil
+}
+
+// pickerStatus is a picker along with their streak in the latest upload of picks, if any.
+type pickerStatus struct {
+	Picker
+	// Streak is nil if the picker is not in the latest upload.
+	Streak *Streak
+}
+
+func listPickers(ctx context.Context, args []string) error {
+	seasonRef, err := mostRecentSeason(ctx)
+	if err != nil {
+		return err
+	}
+
+	streaks := make(map[string]*Streak)
+	week := -1
+	docItr := fsclient.Collection("streak_teams_remaining").Where("season", "==", seasonRef).OrderBy("timestamp", firestore.Desc).Limit(1).Documents(ctx)
+	defer docItr.Stop()
+	picksDoc, err := docItr.Next()
+	switch {
+	case err == iterator.Done:
+		log.Printf("no picks uploaded for season \"%s\"", seasonRef.ID)
+	case err != nil:
+		return err
+	default:
+		var picks Picks
+		if err := picksDoc.DataTo(&picks); err != nil {
+			return err
+		}
+		week = picks.Week
+		log.Printf("latest picks: %s (week %d)", picksDoc.Ref.ID, week)
+
+		streakItr := picksDoc.Ref.Collection("streaks").Documents(ctx)
+		defer streakItr.Stop()
+		for {
+			streakDoc, err := streakItr.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			var s Streak
+			if err := streakDoc.DataTo(&s); err != nil {
+				return err
+			}
+			streaks[s.Picker.ID] = &s
+		}
+	}
+
+	statuses := make([]pickerStatus, 0, len(pickers))
+	for id, p := range pickers {
+		statuses = append(statuses, pickerStatus{Picker: p, Streak: streaks[id]})
+	}
+	return writePickers(os.Stdout, statuses, week)
+}
+
+// writePickers writes a table of pickers sorted by name, with the status of their streaks as of the given week.
+func writePickers(w io.Writer, statuses []pickerStatus, week int) error {
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].NameLuke < statuses[j].NameLuke })
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Name\tAliases\tActive\tWeek\tTeams Remaining\tPick Types Remaining")
+	for _, s := range statuses {
+		aliases := append([]string(nil), s.Aliases...)
+		sort.Strings(aliases)
+		active := "yes"
+		if s.Inactive {
+			active = "no"
+		}
+		if s.Streak == nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\t-\n", s.NameLuke, strings.Join(aliases, ","), active)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%v\n", s.NameLuke, strings.Join(aliases, ","), active, week, len(s.Streak.Remaining), s.Streak.PickTypesRemaining)
+	}
+	return tw.Flush()
+}
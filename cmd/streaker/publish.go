@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// RequestMessage is a request for bts-mc to predict the best streaks of a picker.
+// TODO: Combine with bts-mc
+type RequestMessage struct {
+	Picker string `json:"picker"`
+	Week   *int   `json:"week"`
+	Rules  string `json:"rules,omitempty"`
+}
+
+// publisher publishes messages to a Pub/Sub topic.
+type publisher interface {
+	Publish(ctx context.Context, messages [][]byte) error
+}
+
+// pubsubPublisher publishes messages to a Pub/Sub topic in a single request.
+type pubsubPublisher struct {
+	topics *pubsub.ProjectsTopicsService
+	topic  string
+}
+
+// newPubsubPublisher makes a publisher for a topic. Topics not of the form "projects/PROJECT/topics/TOPIC" are taken to be in the given project.
+func newPubsubPublisher(ctx context.Context, project, topic string, opts ...option.ClientOption) (*pubsubPublisher, error) {
+	svc, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(topic, "projects/") {
+		topic = fmt.Sprintf("projects/%s/topics/%s", project, topic)
+	}
+	return &pubsubPublisher{topics: svc.Projects.Topics, topic: topic}, nil
+}
+
+func (p *pubsubPublisher) Publish(ctx context.Context, messages [][]byte) error {
+	req := &pubsub.PublishRequest{Messages: make([]*pubsub.PubsubMessage, len(messages))}
+	for i, m := range messages {
+		req.Messages[i] = &pubsub.PubsubMessage{Data: base64.StdEncoding.EncodeToString(m)}
+	}
+	resp, err := p.topics.Publish(p.topic, req).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("publish to %s: %v", p.topic, err)
+	}
+	log.Printf("published %d messages to %s: %v", len(resp.MessageIds), p.topic, resp.MessageIds)
+	return nil
+}
+
+// localPublisher is a stand-in for a Pub/Sub topic that writes each message on its own line instead of publishing it.
+type localPublisher struct {
+	w io.Writer
+}
+
+func (p localPublisher) Publish(ctx context.Context, messages [][]byte) error {
+	for _, m := range messages {
+		if _, err := fmt.Fprintf(p.w, "%s\n", m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeRequests makes one bts-mc request message for each picker in alphabetical order, with the pool rules bts-mc should apply.
+func makeRequests(pickers []string, week int, rules string) ([][]byte, error) {
+	sorted := make([]string, len(pickers))
+	copy(sorted, pickers)
+	sort.Strings(sorted)
+
+	messages := make([][]byte, len(sorted))
+	for i, picker := range sorted {
+		w := week
+		m, err := json.Marshal(RequestMessage{Picker: picker, Week: &w, Rules: rules})
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = m
+	}
+	return messages, nil
+}
+
+// publishRequests publishes a bts-mc request for every picker.
+func publishRequests(ctx context.Context, p publisher, pickers []string, week int, rules string) error {
+	messages, err := makeRequests(pickers, week, rules)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, messages)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+func TestTeamScheduleGames(t *testing.T) {
+	teamNames["a"], teamNames["b"], teamNames["c"], teamNames["bye"] = "AAA", "BBB", "CCC", "BYE"
+	ref := func(id string) *firestore.DocumentRef { return &firestore.DocumentRef{ID: id} }
+	ts := TeamSchedule{
+		Team:              ref("a"),
+		Opponents:         []*firestore.DocumentRef{ref("b"), ref("c"), ref("bye"), ref("b")},
+		RelativeLocations: []bts.RelativeLocation{bts.Home, bts.Away, bts.Neutral, bts.Away},
+		Weeks:             []int{0, 0, 1, 2},
+		Statuses:          []string{"scheduled", "cancelled", "scheduled", "scheduled"},
+	}
+	aaa := bts.Team{Name4: "AAA"}
+	games, err := ts.games(aaa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := bts.Schedule{aaa: games}
+	expected := []string{"BBB+@CCC", "", "@BBB"}
+	for week, e := range expected {
+		if n := s.Week(aaa, week).Notation(); n != e {
+			t.Errorf("week %d: expected \"%s\", got \"%s\"", week, e, n)
+		}
+	}
+	if status := s.Games(aaa, 0)[1].Status(); status != bts.Cancelled {
+		t.Errorf("expected game against CCC to be cancelled, got %s", status)
+	}
+
+	ts.Statuses[0] = "rained out"
+	if _, err := ts.games(aaa); err == nil {
+		t.Errorf("expected error for unknown status")
+	}
+	ts.Statuses[0] = "scheduled"
+
+	ts.Weeks = []int{0, 1, 2}
+	if _, err := ts.games(aaa); err == nil {
+		t.Errorf("expected error for fewer weeks than opponents")
+	}
+	ts.Weeks = []int{0, -1, 1, 2}
+	if _, err := ts.games(aaa); err == nil {
+		t.Errorf("expected error for negative week")
+	}
+}
+
+func TestValidatePicker(t *testing.T) {
+	week := 2
+	s := bts.ScheduleFromNotation(map[string][]string{
+		"AAA": {"", "BBB", "BBB", "", "@CCC"},
+		"BBB": {"", "@AAA", "@AAA", "CCC", ""},
+		"CCC": {"", "", "", "@BBB", "AAA"},
+	})
+	s.FilterWeeks(week)
+	aaa, bbb, ccc := bts.Team{Name4: "AAA"}, bts.Team{Name4: "BBB"}, bts.Team{Name4: "CCC"}
+
+	tests := []struct {
+		name        string
+		remaining   bts.Remaining
+		types       []int
+		constraints []bts.PickConstraint
+		errors      []string
+	}{
+		{"valid", bts.Remaining{aaa, bbb, ccc}, []int{0, 3}, nil, nil},
+		{"too few weeks", bts.Remaining{aaa, bbb}, []int{0, 2}, nil, []string{"cover 2 weeks, but 3 weeks remain"}},
+		{"too many picks", bts.Remaining{aaa, bbb}, []int{1, 1, 1}, nil, []string{"number of teams remaining (2) must equal number of picks remaining (3)"}},
+		{"not in schedule", bts.Remaining{aaa, bbb, {Name4: "DDD"}}, []int{0, 3}, nil, []string{"DDD not in schedule"}},
+		{"bad constraint", bts.Remaining{aaa, bbb, ccc}, []int{0, 3}, []bts.PickConstraint{{Must: "CCC", Week: intPtr(1)}}, []string{"team CCC must be picked in week 1, which is not remaining"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := validatePicker(context.Background(), "Person 1", test.remaining, test.types, test.constraints, bts.PoolRules{}, &s, week)
+			if len(errs) != len(test.errors) {
+				t.Fatalf("expected %d errors, got %v", len(test.errors), errs)
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), test.errors[i]) {
+					t.Errorf("expected error containing \"%s\", got \"%v\"", test.errors[i], err)
+				}
+			}
+		})
+	}
+
+	// With AAA-CCC cancelled in the last week, no team can be picked that week unless the pool counts cancelled games as wins.
+	s.Games(aaa, 2)[0].SetStatus(bts.Cancelled)
+	s.Games(ccc, 2)[0].SetStatus(bts.Cancelled)
+	errs := validatePicker(context.Background(), "Person 1", bts.Remaining{aaa, bbb, ccc}, []int{0, 3}, nil, bts.PoolRules{}, &s, week)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "no streak can survive every week") {
+		t.Errorf("expected picker to be eliminated by refunded picks, got %v", errs)
+	}
+	rules, err := bts.ParsePoolRules("cancelled=win")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := validatePicker(context.Background(), "Person 1", bts.Remaining{aaa, bbb, ccc}, []int{0, 3}, nil, rules, &s, week); len(errs) != 0 {
+		t.Errorf("expected no errors when cancelled games count as wins, got %v", errs)
+	}
+}
+
+func TestPublishRequests(t *testing.T) {
+	ctx := context.Background()
+	var b strings.Builder
+	if err := publishRequests(ctx, localPublisher{w: &b}, []string{"Phil K", "Luke M"}, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"picker\":\"Luke M\",\"week\":3}\n{\"picker\":\"Phil K\",\"week\":3}\n"
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	var published []RequestMessage
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		var req pubsub.PublishRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		for _, m := range req.Messages {
+			data, err := base64.StdEncoding.DecodeString(m.Data)
+			if err != nil {
+				t.Error(err)
+			}
+			var rm RequestMessage
+			if err := json.Unmarshal(data, &rm); err != nil {
+				t.Error(err)
+			}
+			published = append(published, rm)
+		}
+		json.NewEncoder(w).Encode(pubsub.PublishResponse{MessageIds: []string{"1", "2"}})
+	}))
+	defer srv.Close()
+
+	pub, err := newPubsubPublisher(ctx, "project", "bts-mc", option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publishRequests(ctx, pub, []string{"Phil K", "Luke M"}, 3, "cancelled=win"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "projects/project/topics/bts-mc:publish") {
+		t.Errorf("expected request to publish to projects/project/topics/bts-mc, got %s", path)
+	}
+	week := 3
+	want := []RequestMessage{{Picker: "Luke M", Week: &week, Rules: "cancelled=win"}, {Picker: "Phil K", Week: &week, Rules: "cancelled=win"}}
+	if !reflect.DeepEqual(published, want) {
+		t.Errorf("expected %v, got %v", want, published)
+	}
+}
+
+func TestCheckNewName(t *testing.T) {
+	lukeNames["Luke M"] = &firestore.DocumentRef{ID: "luke"}
+	lukeNames["Lucas"] = &firestore.DocumentRef{ID: "luke"}
+	defer delete(lukeNames, "Luke M")
+	defer delete(lukeNames, "Lucas")
+
+	for _, name := range []string{"Luke M", "Lucas", "", "  "} {
+		if err := checkNewName(name); err == nil {
+			t.Errorf("name \"%s\": expected error, got none", name)
+		}
+	}
+	if err := checkNewName("Phil K"); err != nil {
+		t.Errorf("name \"Phil K\": unexpected error %v", err)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	ctx := context.Background()
+	for _, args := range [][]string{{"delete", "Luke M"}, {"add"}, {"rename", "Luke M"}, {"list", "all"}} {
+		if err := runCommand(ctx, args); err == nil {
+			t.Errorf("args %v: expected error, got none", args)
+		}
+	}
+}
+
+func TestWritePickers(t *testing.T) {
+	statuses := []pickerStatus{
+		{Picker: Picker{NameLuke: "Phil K", Inactive: true}},
+		{Picker: Picker{NameLuke: "Luke M", Aliases: []string{"Luke", "Lucas"}}, Streak: &Streak{
+			Remaining:          []*firestore.DocumentRef{{ID: "a"}, {ID: "b"}},
+			PickTypesRemaining: []int{0, 2},
+		}},
+	}
+	var b strings.Builder
+	if err := writePickers(&b, statuses, 3); err != nil {
+		t.Fatal(err)
+	}
+	want := `Name    Aliases     Active  Week  Teams Remaining  Pick Types Remaining
+Luke M  Lucas,Luke  yes     3     2                [0 2]
+Phil K              no      -     -                -
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/firestore"
+	"github.com/reallyasi9/beat-the-streak/internal/bts"
+	"google.golang.org/api/iterator"
+)
+
+// TeamSchedule is a team's schedule in Firestore format.
+// TODO: Combine with bts-mc
+type TeamSchedule struct {
+	Team              *firestore.DocumentRef   `firestore:"team"`
+	RelativeLocations []bts.RelativeLocation   `firestore:"locales"`
+	Opponents         []*firestore.DocumentRef `firestore:"opponents"`
+	Weeks             []int                    `firestore:"weeks"`
+	Statuses          []string                 `firestore:"statuses"`
+}
+
+// numWeeks calculates the number of weeks in the schedule.
+// Teams playing more than one game in a week record the week of each game, in which case there must be one week per opponent.
+func (ts TeamSchedule) numWeeks() (int, error) {
+	if len(ts.Weeks) == 0 {
+		return len(ts.Opponents), nil
+	}
+	if len(ts.Weeks) != len(ts.Opponents) {
+		return 0, fmt.Errorf("%d weeks recorded for %d opponents", len(ts.Weeks), len(ts.Opponents))
+	}
+	nWeeks := 0
+	for i, week := range ts.Weeks {
+		if week < 0 {
+			return 0, fmt.Errorf("game %d: negative week %d", i, week)
+		}
+		if week >= nWeeks {
+			nWeeks = week + 1
+		}
+	}
+	return nWeeks, nil
+}
+
+// loadSchedule loads the most recent schedule stored for a season, with teams named as in the teams collection.
+func loadSchedule(ctx context.Context, seasonRef *firestore.DocumentRef) (bts.Schedule, error) {
+	itr := fsclient.Collection("schedules").Where("season", "==", seasonRef).OrderBy("timestamp", firestore.Desc).Limit(1).Documents(ctx)
+	schedDoc, err := itr.Next()
+	itr.Stop()
+	if err == iterator.Done {
+		// Schedules stored without a timestamp are only found unordered.
+		itr = fsclient.Collection("schedules").Where("season", "==", seasonRef).Limit(1).Documents(ctx)
+		schedDoc, err = itr.Next()
+		itr.Stop()
+	}
+	if err == iterator.Done {
+		return nil, fmt.Errorf("loadSchedule: no schedule stored for season \"%s\"", seasonRef.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("latest schedule on record: \"%s\"", schedDoc.Ref.ID)
+
+	teamDocs, err := schedDoc.Ref.Collection("teams").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := make(bts.Schedule)
+	for _, doc := range teamDocs {
+		var ts TeamSchedule
+		if err := doc.DataTo(&ts); err != nil {
+			return nil, err
+		}
+		team := bts.Team{Name4: teamNames[ts.Team.ID]}
+		schedule[team], err = ts.games(team)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return schedule, nil
+}
+
+// games arranges the team's games by week. Teams playing more than one game in a week record the week of each game.
+// Games are given the statuses stored with them, so that pool rules can be applied to games not played as scheduled.
+func (ts TeamSchedule) games(team bts.Team) ([][]*bts.Game, error) {
+	nWeeks, err := ts.numWeeks()
+	if err != nil {
+		return nil, fmt.Errorf("team %s: %v", team.Name(), err)
+	}
+	weeks := make([][]*bts.Game, nWeeks)
+	for i := range weeks {
+		weeks[i] = make([]*bts.Game, 0, 1)
+	}
+	for i, opp := range ts.Opponents {
+		op := bts.Team{Name4: teamNames[opp.ID]}
+		if op == bts.BYE {
+			continue
+		}
+		week := i
+		if i < len(ts.Weeks) {
+			week = ts.Weeks[i]
+		}
+		loc := bts.RelativeLocation(bts.Neutral)
+		if i < len(ts.RelativeLocations) {
+			loc = ts.RelativeLocations[i]
+		}
+		game := bts.NewGame(team, op, loc)
+		if i < len(ts.Statuses) {
+			status, err := bts.ParseGameStatus(ts.Statuses[i])
+			if err != nil {
+				return nil, fmt.Errorf("team %s week %d: %v", team.Name(), week, err)
+			}
+			game.SetStatus(status)
+		}
+		weeks[week] = append(weeks[week], game)
+	}
+	return weeks, nil
+}
+
+// playableModel predicts that every game played can be won, so that only the schedule and the pool rules decide whether a pick can win.
+type playableModel struct{}
+
+func (playableModel) Predict(game *bts.Game) (float64, float64) {
+	if game.Team(0) == bts.BYE || game.Team(1) == bts.BYE {
+		return 0., 0.
+	}
+	return 1., 0.
+}
+
+func (m playableModel) MostLikelyOutcome(game *bts.Game) (bts.Team, float64, float64) {
+	prob, spread := m.Predict(game)
+	if prob == 0 {
+		return bts.BYE, prob, spread
+	}
+	return game.Team(0), prob, spread
+}
+
+// validatePicker checks that a picker's remaining teams, pick types, and constraints are consistent with each other and with the schedule,
+// as bts-mc will check them, and returns every problem found.
+// Like bts-mc, picks that the pool rules would refund are not allowed, and the picker must still have a streak that can survive every week.
+// The schedule is expected to be filtered so that its first week is the season week firstWeek.
+func validatePicker(ctx context.Context, name string, remaining bts.Remaining, types []int, constraints []bts.PickConstraint, rules bts.PoolRules, schedule *bts.Schedule, firstWeek int) []error {
+	errs := make([]error, 0)
+	nWeeks := schedule.NumWeeks()
+
+	nTypeWeeks := 0
+	for _, n := range types {
+		nTypeWeeks += n
+	}
+	if nTypeWeeks != nWeeks {
+		errs = append(errs, fmt.Errorf("picker \"%s\": pick types cover %d weeks, but %d weeks remain after week %d", name, nTypeWeeks, nWeeks, firstWeek))
+	}
+
+	for _, team := range remaining {
+		if _, ok := (*schedule)[team]; !ok {
+			errs = append(errs, fmt.Errorf("picker \"%s\": remaining team %s not in schedule", name, team.Name()))
+		}
+	}
+
+	player, err := bts.NewPlayer(name, remaining, types, nWeeks)
+	if err != nil {
+		return append(errs, fmt.Errorf("picker \"%s\": %v", name, err))
+	}
+	if len(errs) > 0 {
+		// Constraints cannot be checked against teams that are not in the schedule.
+		return errs
+	}
+	if err := player.Constrain(schedule, constraints, firstWeek); err != nil {
+		return append(errs, err)
+	}
+	if err := player.ApplyRules(schedule, rules); err != nil {
+		return append(errs, err)
+	}
+	alive, err := player.Alive(ctx, bts.MakePredictions(schedule, rules.Model(playableModel{})))
+	if err != nil {
+		return append(errs, err)
+	}
+	if !alive {
+		errs = append(errs, fmt.Errorf("picker \"%s\": no streak can survive every week under pool rules %s", name, rules))
+	}
+	return errs
+}
@@ -0,0 +1,228 @@
+package bts
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PickConstraint restricts the weeks in which a player may pick a team.
+// Exactly one of Must or Never is set.
+// Week numbers are season week numbers (starting at 0 for preseason), not weeks relative to the current week.
+type PickConstraint struct {
+	// Must is a team that must be picked in Week.
+	Must string `yaml:"must,omitempty"`
+
+	// Never is a team that may not be picked in any week matching all of the given Week, After, Before, and Opponent filters.
+	// If no filters are given, the team may not be picked at all.
+	Never string `yaml:"never,omitempty"`
+
+	// Week is the only week a Must team can be picked, or a week a Never team cannot be picked.
+	Week *int `yaml:"week,omitempty"`
+
+	// After, if given, limits a Never constraint to weeks after this week.
+	After *int `yaml:"after,omitempty"`
+
+	// Before, if given, limits a Never constraint to weeks before this week.
+	Before *int `yaml:"before,omitempty"`
+
+	// Opponent, if given, limits a Never constraint to weeks the team plays this opponent.
+	Opponent string `yaml:"opponent,omitempty"`
+}
+
+// Validate checks that the constraint is well-formed.
+func (c PickConstraint) Validate() error {
+	switch {
+	case c.Must != "" && c.Never != "":
+		return fmt.Errorf("constraint cannot be both must (%s) and never (%s)", c.Must, c.Never)
+	case c.Must == "" && c.Never == "":
+		return fmt.Errorf("constraint must be one of must or never")
+	case c.Must != "" && c.Week == nil:
+		return fmt.Errorf("must constraint for team %s requires a week", c.Must)
+	case c.Must != "" && (c.After != nil || c.Before != nil || c.Opponent != ""):
+		return fmt.Errorf("must constraint for team %s only accepts a week", c.Must)
+	}
+	return nil
+}
+
+// Team returns the name of the team the constraint applies to.
+func (c PickConstraint) Team() string {
+	if c.Must != "" {
+		return c.Must
+	}
+	return c.Never
+}
+
+// matches reports whether a Never constraint applies to the given season week and opponent.
+func (c PickConstraint) matches(week int, opponent Team) bool {
+	if c.Week != nil && week != *c.Week {
+		return false
+	}
+	if c.After != nil && week <= *c.After {
+		return false
+	}
+	if c.Before != nil && week >= *c.Before {
+		return false
+	}
+	if c.Opponent != "" && opponent.Name() != c.Opponent {
+		return false
+	}
+	return true
+}
+
+// ConstraintMap associates a player's name with that player's pick constraints.
+type ConstraintMap map[string][]PickConstraint
+
+// MakeConstraints parses a YAML file of pick constraints by player name.
+func MakeConstraints(constraintFile string) (ConstraintMap, error) {
+	constraintYaml, err := ioutil.ReadFile(constraintFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cm := make(ConstraintMap)
+	err = yaml.Unmarshal(constraintYaml, cm)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, constraints := range cm {
+		for _, c := range constraints {
+			if err := c.Validate(); err != nil {
+				return nil, fmt.Errorf("player %s: %v", name, err)
+			}
+		}
+	}
+
+	return cm, nil
+}
+
+// Availability records the weeks, relative to the first week of a streak, in which each team may be picked.
+// A nil Availability allows every team to be picked in every week.
+type Availability map[Team][]bool
+
+// NewAvailability determines the weeks in which the remaining teams may be picked.
+// Teams can never be picked in weeks they have a bye in the schedule, and are further restricted by the given constraints.
+// The schedule is expected to be filtered so that its first week is the season week firstWeek.
+func NewAvailability(remaining Remaining, s *Schedule, constraints []PickConstraint, firstWeek int) (Availability, error) {
+	nWeeks := s.NumWeeks()
+	a := make(Availability)
+	for _, team := range remaining {
+		if _, ok := (*s)[team]; !ok {
+			return nil, fmt.Errorf("team %s not in schedule", team.Name())
+		}
+		weeks := make([]bool, nWeeks)
+		for week := range weeks {
+			weeks[week] = s.PlaysIn(team, week)
+		}
+		a[team] = weeks
+	}
+
+	for _, c := range constraints {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		team := Team{Name4: c.Team()}
+		weeks, ok := a[team]
+		if !ok {
+			return nil, fmt.Errorf("constrained team %s not remaining", team.Name())
+		}
+
+		if c.Must != "" {
+			must := *c.Week - firstWeek
+			if must < 0 || must >= nWeeks {
+				return nil, fmt.Errorf("team %s must be picked in week %d, which is not remaining", team.Name(), *c.Week)
+			}
+			for week := range weeks {
+				weeks[week] = weeks[week] && week == must
+			}
+			continue
+		}
+
+		for week := range weeks {
+			for _, g := range s.Games(team, week) {
+				if c.matches(week+firstWeek, g.Team(1)) {
+					weeks[week] = false
+				}
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// Allows reports whether the team may be picked in the given week.
+// Picking no team is always allowed.
+func (a Availability) Allows(team Team, week int) bool {
+	if a == nil || team == NONE {
+		return true
+	}
+	weeks, ok := a[team]
+	if !ok || week < 0 || week >= len(weeks) {
+		return true
+	}
+	return weeks[week]
+}
+
+// Feasible reports whether every team in the streak is picked in an allowed week.
+func (a Availability) Feasible(s *Streak) bool {
+	if a == nil {
+		return true
+	}
+	pick := 0
+	for week, ppw := range s.numberOfPicks {
+		for _, team := range s.teamOrder[pick : pick+ppw] {
+			if !a.Allows(team, week) {
+				return false
+			}
+		}
+		pick += ppw
+	}
+	return true
+}
+
+// assign finds an assignment of the teams to weeks with the given numbers of picks per week such that every team is picked in an allowed week.
+// It returns the teams in streak order and whether such an assignment exists.
+func (a Availability) assign(teams Remaining, picksPerWeek []int) (TeamList, bool) {
+	slotWeeks := make([]int, 0, len(teams))
+	for week, ppw := range picksPerWeek {
+		for i := 0; i < ppw; i++ {
+			slotWeeks = append(slotWeeks, week)
+		}
+	}
+	if len(slotWeeks) != len(teams) {
+		return nil, false
+	}
+
+	// Bipartite matching of teams to slots by augmenting paths (Kuhn's algorithm).
+	slotTeam := make([]int, len(slotWeeks))
+	for i := range slotTeam {
+		slotTeam[i] = -1
+	}
+	var augment func(t int, visited []bool) bool
+	augment = func(t int, visited []bool) bool {
+		for slot, week := range slotWeeks {
+			if visited[slot] || !a.Allows(teams[t], week) {
+				continue
+			}
+			visited[slot] = true
+			if slotTeam[slot] < 0 || augment(slotTeam[slot], visited) {
+				slotTeam[slot] = t
+				return true
+			}
+		}
+		return false
+	}
+	for t := range teams {
+		if !augment(t, make([]bool, len(slotWeeks))) {
+			return nil, false
+		}
+	}
+
+	out := make(TeamList, len(slotTeam))
+	for slot, t := range slotTeam {
+		out[slot] = teams[t]
+	}
+	return out, true
+}
@@ -0,0 +1,182 @@
+package bts
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSchedule makes a schedule from compact notation without reading a file.
+func testSchedule(s map[string][]string) *Schedule {
+	sched := ScheduleFromNotation(s)
+	return &sched
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestNewAvailability(t *testing.T) {
+	s := testSchedule(map[string][]string{
+		"AAA": {"!BBB", ">CCC", "<DDD", "", "@EEE"},
+		"BBB": {"!AAA", "", "!EEE", "@DDD", "CCC"},
+		"CCC": {"DDD", "<AAA", "", "@EEE", "@BBB"},
+		"DDD": {"@CCC", "@EEE", ">AAA", "BBB", ""},
+		"EEE": {"", "DDD", "!BBB", "CCC", "AAA"},
+	})
+	s.FilterWeeks(1)
+	rem := Remaining{Team{"AAA"}, Team{"BBB"}, Team{"CCC"}, Team{"DDD"}}
+
+	constraints := []PickConstraint{
+		{Must: "AAA", Week: intPtr(4)},
+		{Never: "BBB", Opponent: "DDD"},
+		{Never: "CCC", After: intPtr(3)},
+	}
+	a, err := NewAvailability(rem, s, constraints, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[Team][]bool{
+		Team{"AAA"}: {false, false, false, true},
+		Team{"BBB"}: {false, true, false, true},
+		Team{"CCC"}: {true, false, true, false},
+		Team{"DDD"}: {true, true, true, false},
+	}
+	for team, weeks := range expected {
+		for week, want := range weeks {
+			if got := a.Allows(team, week); got != want {
+				t.Errorf("team %s week %d: expected %t, got %t", team.Name(), week, want, got)
+			}
+		}
+	}
+	if !a.Allows(NONE, 0) {
+		t.Errorf("expected picking no team to be allowed")
+	}
+
+	bad := [][]PickConstraint{
+		{{Must: "AAA"}},
+		{{Must: "AAA", Week: intPtr(0)}},
+		{{Never: "EEE"}},
+		{{Must: "AAA", Never: "BBB", Week: intPtr(2)}},
+	}
+	for _, c := range bad {
+		if _, err := NewAvailability(rem, s, c, 1); err == nil {
+			t.Errorf("expected error for constraints %v", c)
+		}
+	}
+}
+
+func TestConstrainedSearch(t *testing.T) {
+	s := testSchedule(map[string][]string{
+		"AAA": {"BBB", "CCC", "", "DDD", "EEE"},
+		"BBB": {"@AAA", "DDD", "CCC", "", "EEE"},
+		"CCC": {"DDD", "@AAA", "@BBB", "EEE", ""},
+		"DDD": {"@CCC", "@BBB", "EEE", "@AAA", "FFF"},
+		"EEE": {"FFF", "", "@DDD", "@CCC", "@AAA"},
+	})
+	rem := Remaining{Team{"AAA"}, Team{"BBB"}, Team{"CCC"}, Team{"DDD"}, Team{"EEE"}}
+	p, err := NewPlayer("P", rem, []int{1, 3, 1}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Constrain(s, []PickConstraint{{Must: "DDD", Week: intPtr(4)}, {Never: "AAA", Before: intPtr(1)}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pred := randomPredictions(TeamList(rem), 5, 3)
+	avail := p.Availability()
+
+	// Brute force for comparison
+	want := NewTopStreaks(5)
+	feasible := 0
+	ctx := context.Background()
+	for wt := range p.WeekTypeIterator(ctx) {
+		for order := range p.RemainingIterator(ctx) {
+			st := NewStreak(rem, wt)
+			st.PermuteTeamOrder(order)
+			if !avail.Feasible(st) {
+				continue
+			}
+			feasible++
+			prob, spread := SummarizeStreak(pred, st)
+			want.Push(StreakResult{Streak: st, Score: Score{Value: prob, Tiebreak: spread}, Probability: prob, Spread: spread})
+		}
+	}
+	if feasible == 0 {
+		t.Fatal("expected some feasible streaks")
+	}
+
+	for _, shards := range []int{1, 4, 9} {
+		got, err := SearchShards(ctx, p, pred, MaxSurvival{}, shards, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := want.Results()
+		g := got.Results()
+		if len(g) != len(w) {
+			t.Fatalf("%d shards: expected %d results, got %d", shards, len(w), len(g))
+		}
+		for i := range w {
+			if g[i].Streak.Hash() != w[i].Streak.Hash() {
+				t.Errorf("%d shards: result %d expected %v, got %v", shards, i, w[i], g[i])
+			}
+		}
+	}
+
+	st, err := p.FeasibleStreak(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := rand.NewSource(0)
+	for i := 0; i < 1000; i++ {
+		if !avail.Feasible(st) {
+			t.Fatalf("streak %s is not feasible", st)
+		}
+		st.PerturbateAvailable(src, true, avail)
+	}
+}
+
+func TestMakeConstraints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "constraints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "constraints.yaml")
+	err = ioutil.WriteFile(fn, []byte(`Person 1:
+  - must: AAA
+    week: 2
+  - never: BBB
+    opponent: CCC
+Person 2:
+  - never: DDD
+    after: 3
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := MakeConstraints(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cm["Person 1"]) != 2 || len(cm["Person 2"]) != 1 {
+		t.Fatalf("unexpected constraints %v", cm)
+	}
+	if c := cm["Person 2"][0]; c.Never != "DDD" || c.After == nil || *c.After != 3 {
+		t.Errorf("unexpected constraint %v", c)
+	}
+
+	err = ioutil.WriteFile(fn, []byte("Person 1:\n  - must: AAA\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MakeConstraints(fn); err == nil {
+		t.Errorf("expected error for must constraint without week")
+	}
+}
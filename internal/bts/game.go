@@ -1,6 +1,9 @@
 package bts
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // RelativeLocation describes where a game is being played relative to one team's home field.
 type RelativeLocation int
@@ -22,21 +25,81 @@ const (
 	Away = -2
 )
 
+// GameStatus describes whether a game is played as scheduled.
+type GameStatus int
+
+const (
+	// Scheduled games are expected to be played as scheduled.
+	Scheduled GameStatus = iota
+
+	// Postponed games will not be played as scheduled, but may be made up later.
+	Postponed
+
+	// Cancelled games will not be played.
+	Cancelled
+
+	// NoContest games were started but do not count.
+	NoContest
+)
+
+var gameStatusNames = map[GameStatus]string{
+	Scheduled: "scheduled",
+	Postponed: "postponed",
+	Cancelled: "cancelled",
+	NoContest: "no-contest",
+}
+
+func (s GameStatus) String() string {
+	if name, ok := gameStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("GameStatus(%d)", int(s))
+}
+
+// ParseGameStatus parses the name of a game status. An empty name is Scheduled.
+func ParseGameStatus(name string) (GameStatus, error) {
+	if name == "" {
+		return Scheduled, nil
+	}
+	for s, n := range gameStatusNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return Scheduled, fmt.Errorf("unknown game status \"%s\"", name)
+}
+
 // Game represents a matchup between two teams.
 type Game struct {
 	team1    Team
 	team2    Team
 	location RelativeLocation
+
+	// travel is the distance in miles each team travels to the game, if known.
+	travel    [2]float64
+	hasTravel bool
+
+	// kickoff is the date and time the game starts, or zero if unknown.
+	kickoff time.Time
+	// venue is the name of the venue where the game is played, or empty if unknown.
+	venue string
+	// status is whether the game is played as scheduled.
+	status GameStatus
 }
 
 // NULLGAME represents a game that doesn't exsit.  Go figure.
-var NULLGAME = Game{NONE, NONE, Neutral}
+var NULLGAME = Game{team1: NONE, team2: NONE, location: Neutral}
 
 // NewGame makes a game between two teams.
 func NewGame(team1, team2 Team, locRelTeam1 RelativeLocation) *Game {
 	return &Game{team1: team1, team2: team2, location: locRelTeam1}
 }
 
+// NewGameWithTravel makes a game between two teams and records the distance in miles each team travels to the game.
+func NewGameWithTravel(team1, team2 Team, locRelTeam1 RelativeLocation, travel1, travel2 float64) *Game {
+	return &Game{team1: team1, team2: team2, location: locRelTeam1, travel: [2]float64{travel1, travel2}, hasTravel: true}
+}
+
 // Team returns a given team.
 func (g *Game) Team(t int) Team {
 	switch t {
@@ -60,3 +123,62 @@ func (g *Game) LocationRelativeToTeam(t int) RelativeLocation {
 		panic(fmt.Errorf("team %d is not a valid team", t))
 	}
 }
+
+// Travel returns the distance in miles the given team travels to the game, and whether the distance is known.
+func (g *Game) Travel(t int) (float64, bool) {
+	switch t {
+	case 0, 1:
+		return g.travel[t], g.hasTravel
+	default:
+		panic(fmt.Errorf("team %d is not a valid team", t))
+	}
+}
+
+// RelativeTravel returns how much farther the given team's opponent travels than the given team, relative to the total distance traveled,
+// and whether the distances are known. It ranges from 1 when the team travels nowhere to -1 when the opponent travels nowhere.
+func (g *Game) RelativeTravel(t int) (float64, bool) {
+	switch t {
+	case 0:
+		return relativeTravel(g.travel[0], g.travel[1]), g.hasTravel
+	case 1:
+		return relativeTravel(g.travel[1], g.travel[0]), g.hasTravel
+	default:
+		panic(fmt.Errorf("team %d is not a valid team", t))
+	}
+}
+
+// SetTravel sets the distance in miles each team travels to the game.
+func (g *Game) SetTravel(travel1, travel2 float64) {
+	g.travel = [2]float64{travel1, travel2}
+	g.hasTravel = true
+}
+
+// Kickoff returns the date and time the game starts, and whether it is known.
+func (g *Game) Kickoff() (time.Time, bool) {
+	return g.kickoff, !g.kickoff.IsZero()
+}
+
+// SetKickoff sets the date and time the game starts.
+func (g *Game) SetKickoff(t time.Time) {
+	g.kickoff = t
+}
+
+// Venue returns the name of the venue where the game is played, or an empty string if unknown.
+func (g *Game) Venue() string {
+	return g.venue
+}
+
+// SetVenue sets the name of the venue where the game is played.
+func (g *Game) SetVenue(name string) {
+	g.venue = name
+}
+
+// Status returns whether the game is played as scheduled.
+func (g *Game) Status() GameStatus {
+	return g.status
+}
+
+// SetStatus sets whether the game is played as scheduled.
+func (g *Game) SetStatus(s GameStatus) {
+	g.status = s
+}
@@ -1,8 +1,12 @@
 package bts
 
 import (
+	"context"
 	"math/big"
+	"math/rand"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func check(p1 []int, p2 []int) bool {
@@ -17,6 +21,29 @@ func check(p1 []int, p2 []int) bool {
 	return true
 }
 
+// lexLess reports whether p1 comes strictly before p2 in lexicographic order.
+func lexLess(p1 []int, p2 []int) bool {
+	for i := range p1 {
+		if p1[i] != p2[i] {
+			return p1[i] < p2[i]
+		}
+	}
+	return false
+}
+
+// leaked waits for the number of running goroutines to fall back to at most the given number.
+// It returns the number of goroutines in excess of that number if they do not exit in a reasonable amount of time.
+func leaked(n int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		excess := runtime.NumGoroutine() - n
+		if excess <= 0 || time.Now().After(deadline) {
+			return excess
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestIdenticalPermutor(t *testing.T) {
 	s := []int{0, 0, 0, 1, 1, 2}
 	p := NewIdenticalPermutor(3, 2, 1)
@@ -27,7 +54,7 @@ func TestIdenticalPermutor(t *testing.T) {
 	}
 
 	// First should be identical
-	itr := p.Iterator()
+	itr := p.Iterator(context.Background())
 	test := <-itr
 	if !check(s, test) {
 		t.Fatalf("expected %v, got %v", s, test)
@@ -44,7 +71,7 @@ func TestIdenticalPermutor(t *testing.T) {
 
 	// Should be only 4 of these
 	n := 0
-	itr2 := p2.Iterator()
+	itr2 := p2.Iterator(context.Background())
 	for test = range itr2 {
 		t.Log(test)
 		n++
@@ -62,7 +89,7 @@ func TestIdenticalPermutor(t *testing.T) {
 	// Should be (a+b)!/a!/b! of these
 	p3 := NewIdenticalPermutor(2, 3)
 	n = 0
-	itr3 := p3.Iterator()
+	itr3 := p3.Iterator(context.Background())
 	for test = range itr3 {
 		t.Log(test)
 		n++
@@ -80,12 +107,91 @@ func TestIdenticalPermutor(t *testing.T) {
 	}
 }
 
+func TestIdenticalPermutorCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	p := NewIdenticalPermutor(4, 3, 3)
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		itr := p.Iterator(ctx)
+		<-itr
+		cancel()
+	}
+
+	if n := leaked(before); n > 0 {
+		t.Errorf("expected no leaked goroutines, got %d", n)
+	}
+}
+
+func TestIdenticalPermutorRank(t *testing.T) {
+	p := NewIdenticalPermutor(2, 0, 3, 1)
+
+	var prev []int
+	seen := make(map[uint64]bool)
+	n := p.NumberOfPermutations().Int64()
+	for i := int64(0); i < n; i++ {
+		perm, err := p.Permutation(big.NewInt(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev != nil && !lexLess(prev, perm) {
+			t.Errorf("expected %v to follow %v lexicographically", perm, prev)
+		}
+		prev = perm
+		seen[hash(perm)] = true
+
+		rank, err := p.Rank(perm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rank.Int64() != i {
+			t.Errorf("expected rank %d, got %v", i, rank)
+		}
+	}
+
+	count := 0
+	for perm := range p.Iterator(context.Background()) {
+		count++
+		if !seen[hash(perm)] {
+			t.Errorf("permutation %v not produced by ranking", perm)
+		}
+	}
+	if int64(count) != n {
+		t.Errorf("expected %d permutations, got %d", n, count)
+	}
+
+	if _, err := p.Permutation(big.NewInt(-1)); err == nil {
+		t.Errorf("expected error for rank -1")
+	}
+	if _, err := p.Rank([]int{0, 0, 1, 2, 2, 3}); err == nil {
+		t.Errorf("expected error for invalid permutation")
+	}
+}
+
+func TestIdenticalPermutorRandom(t *testing.T) {
+	p := NewIdenticalPermutor(1, 2)
+	rng := rand.New(rand.NewSource(0))
+
+	counts := make(map[uint64]int)
+	for i := 0; i < 3000; i++ {
+		counts[hash(p.Random(rng))]++
+	}
+	if len(counts) != 3 {
+		t.Fatalf("expected 3 distinct permutations, got %d", len(counts))
+	}
+	for _, c := range counts {
+		if c < 900 || c > 1100 {
+			t.Errorf("expected roughly 1000 draws per permutation, got %d", c)
+		}
+	}
+}
+
 func BenchmarkIdenticalPermutor10(b *testing.B) {
 	p := NewIdenticalPermutor(4, 3, 3)
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		itr := p.Iterator()
+		itr := p.Iterator(context.Background())
 		for range itr {
 			// Count them all!
 		}
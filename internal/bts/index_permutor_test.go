@@ -1,7 +1,9 @@
 package bts
 
 import (
+	"context"
 	"math/big"
+	"runtime"
 	"testing"
 )
 
@@ -15,7 +17,7 @@ func TestIndexPermutor(t *testing.T) {
 	}
 
 	// First should be identical
-	itr := p.Iterator()
+	itr := p.Iterator(context.Background())
 	test := <-itr
 	if !check(s, test) {
 		t.Errorf("expected %v, got %v", s, test)
@@ -32,7 +34,7 @@ func TestIndexPermutor(t *testing.T) {
 
 	// Should be only 6 of these
 	n := 0
-	itr2 := p2.Iterator()
+	itr2 := p2.Iterator(context.Background())
 	for test = range itr2 {
 		t.Log(test)
 		n++
@@ -50,7 +52,7 @@ func TestIndexPermutor(t *testing.T) {
 	// Should be a! of these
 	p3 := NewIndexPermutor(6)
 	n = 0
-	itr3 := p3.Iterator()
+	itr3 := p3.Iterator(context.Background())
 	for test = range itr3 {
 		// t.Log(test)
 		n++
@@ -66,12 +68,86 @@ func TestIndexPermutor(t *testing.T) {
 	}
 }
 
+func TestIndexPermutorCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	p := NewIndexPermutor(10)
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		itr := p.Iterator(ctx)
+		<-itr
+		<-itr
+		cancel()
+	}
+
+	if n := leaked(before); n > 0 {
+		t.Errorf("expected no leaked goroutines, got %d", n)
+	}
+}
+
+func TestTeamPermuteCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	tl := TeamList{Team{"A"}, Team{"B"}, Team{"C"}, Team{"D"}, Team{"E"}, Team{"F"}, Team{"G"}}
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := make(chan TeamList)
+		go TeamPermute(ctx, tl, c)
+		<-c
+		cancel()
+	}
+
+	if n := leaked(before); n > 0 {
+		t.Errorf("expected no leaked goroutines, got %d", n)
+	}
+}
+
+func TestIndexPermutorRank(t *testing.T) {
+	p := NewIndexPermutor(5)
+
+	var prev []int
+	seen := make(map[uint64]bool)
+	n := p.NumberOfPermutations().Int64()
+	for i := int64(0); i < n; i++ {
+		perm, err := p.Permutation(big.NewInt(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev != nil && !lexLess(prev, perm) {
+			t.Errorf("expected %v to follow %v lexicographically", perm, prev)
+		}
+		prev = perm
+		seen[hash(perm)] = true
+
+		rank, err := p.Rank(perm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rank.Int64() != i {
+			t.Errorf("expected rank %d, got %v", i, rank)
+		}
+	}
+
+	for perm := range p.Iterator(context.Background()) {
+		if !seen[hash(perm)] {
+			t.Errorf("permutation %v not produced by ranking", perm)
+		}
+	}
+
+	if _, err := p.Permutation(big.NewInt(n)); err == nil {
+		t.Errorf("expected error for rank %d", n)
+	}
+	if _, err := p.Rank([]int{0, 1, 1, 2, 3}); err == nil {
+		t.Errorf("expected error for invalid permutation")
+	}
+}
+
 func BenchmarkPermuteAll10(b *testing.B) {
 	p := NewIndexPermutor(10)
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		itr := p.Iterator()
+		itr := p.Iterator(context.Background())
 		for range itr {
 			// Count them all!
 		}
@@ -23,6 +23,8 @@ type GaussianSpreadModel struct {
 	homeBias  float64
 	closeBias float64
 	ratings   map[Team]float64
+	// travelScaled is whether the location bias scales with the relative distance the teams travel.
+	travelScaled bool
 }
 
 // NewGaussianSpreadModel makes a model.
@@ -30,6 +32,15 @@ func NewGaussianSpreadModel(ratings map[Team]float64, stdDev, homeBias, closeBia
 	return &GaussianSpreadModel{ratings: ratings, dist: prob.Normal{Mu: 0, Sigma: stdDev}, homeBias: homeBias, closeBias: closeBias}
 }
 
+// NewTravelSpreadModel makes a model where the location bias scales with how much farther one team travels to the game than the other.
+// A team that travels nowhere gets the full home bias, and teams that travel equally far get none.
+// Games without known travel distances fall back to a close bias of half the home bias.
+func NewTravelSpreadModel(ratings map[Team]float64, stdDev, homeBias float64) *GaussianSpreadModel {
+	m := NewGaussianSpreadModel(ratings, stdDev, homeBias, homeBias/2.)
+	m.travelScaled = true
+	return m
+}
+
 // Predict returns the probability and spread for team1.
 func (m GaussianSpreadModel) Predict(game *Game) (float64, float64) {
 	if game.Team(0) == BYE || game.Team(1) == BYE {
@@ -61,6 +72,11 @@ func (m GaussianSpreadModel) MostLikelyOutcome(game *Game) (Team, float64, float
 
 func (m GaussianSpreadModel) spread(game *Game) float64 {
 	diff := m.ratings[game.Team(0)] - m.ratings[game.Team(1)]
+	if m.travelScaled {
+		if rt, ok := game.RelativeTravel(0); ok {
+			return diff + m.homeBias*rt
+		}
+	}
 	switch game.LocationRelativeToTeam(0) {
 	case Home:
 		diff += m.homeBias
@@ -142,7 +158,7 @@ func scrapeParameters(url string, modelName string) (float64, float64, error) {
 
 func (m GaussianSpreadModel) String() string {
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("home bias: %f; close bias: %f;\n", m.homeBias, m.closeBias))
+	b.WriteString(fmt.Sprintf("home bias: %f; close bias: %f; travel scaled: %t;\n", m.homeBias, m.closeBias, m.travelScaled))
 	for t, r := range m.ratings {
 		b.WriteString(fmt.Sprintf("%s: %f\n", t, r))
 	}
@@ -0,0 +1,153 @@
+package bts
+
+import (
+	"fmt"
+	"math"
+)
+
+// Score is the value of a streak under an Objective.
+// Higher values are better, and ties are broken by higher tiebreakers.
+type Score struct {
+	Value    float64
+	Tiebreak float64
+}
+
+// Better reports whether the score ranks above another.
+func (s Score) Better(o Score) bool {
+	if s.Value == o.Value {
+		return s.Tiebreak > o.Tiebreak
+	}
+	return s.Value > o.Value
+}
+
+// Objective scores a streak from the probability of winning every pick in each week of the streak and the total spread of all picks.
+type Objective interface {
+	Score(weekProbs []float64, spread float64) Score
+}
+
+// WeekProbabilities calculates the probability of winning every pick in each week of the streak and the total spread of all picks.
+// If buf is large enough, it is used to store the probabilities to avoid allocation.
+func WeekProbabilities(p *Predictions, s *Streak, buf []float64) ([]float64, float64) {
+	if cap(buf) < s.NumWeeks() {
+		buf = make([]float64, s.NumWeeks())
+	}
+	buf = buf[:s.NumWeeks()]
+	spread := 0.
+	for week := range buf {
+		prob := 1.
+		for _, pick := range s.GetWeek(week) {
+			prob *= p.GetProbability(pick, week)
+			spread += p.GetSpread(pick, week)
+		}
+		buf[week] = prob
+	}
+	return buf, spread
+}
+
+func product(x []float64) float64 {
+	out := 1.
+	for _, v := range x {
+		out *= v
+	}
+	return out
+}
+
+// MaxSurvival maximizes the probability of surviving the entire streak, breaking ties by total spread.
+type MaxSurvival struct{}
+
+// Score implements Objective.
+func (MaxSurvival) Score(weekProbs []float64, spread float64) Score {
+	return Score{Value: product(weekProbs), Tiebreak: spread}
+}
+
+// ExpectedWeeks maximizes the expected number of weeks survived, breaking ties by total spread.
+type ExpectedWeeks struct{}
+
+// Score implements Objective.
+func (ExpectedWeeks) Score(weekProbs []float64, spread float64) Score {
+	cp := 1.
+	sum := 0.
+	for _, p := range weekProbs {
+		cp *= p
+		sum += cp
+	}
+	return Score{Value: sum, Tiebreak: spread}
+}
+
+// MaxMinWeek maximizes the smallest probability of surviving any single week, breaking ties by the probability of surviving the entire streak.
+type MaxMinWeek struct{}
+
+// Score implements Objective.
+func (MaxMinWeek) Score(weekProbs []float64, spread float64) Score {
+	min := math.Inf(1)
+	if len(weekProbs) == 0 {
+		min = 1.
+	}
+	for _, p := range weekProbs {
+		min = math.Min(min, p)
+	}
+	return Score{Value: min, Tiebreak: product(weekProbs)}
+}
+
+// Outlast maximizes the probability of surviving strictly longer than every one of a set of opponents, breaking ties by the probability of surviving the entire streak.
+// Opponents are described by their survival curves: the probability of surviving through each week, starting with the first week of the streak.
+// Opponents are assumed to survive independently of one another and of the player.
+type Outlast struct {
+	opponents [][]float64
+}
+
+// NewOutlast creates an Outlast objective from the survival curves of the opponents.
+func NewOutlast(curves ...[]float64) *Outlast {
+	return &Outlast{opponents: curves}
+}
+
+// survival returns the probability of surviving through the given week.
+// Curves that end early are assumed to survive at the last given probability.
+func survival(curve []float64, week int) float64 {
+	if len(curve) == 0 {
+		return 1.
+	}
+	if week >= len(curve) {
+		return curve[len(curve)-1]
+	}
+	return curve[week]
+}
+
+// Score implements Objective.
+func (o *Outlast) Score(weekProbs []float64, spread float64) Score {
+	// P(outlast) = sum over weeks t of P(last opponent eliminated in week t) * P(player survives week t).
+	value := 0.
+	cp := 1.
+	prevAllOut := 0.
+	for week, p := range weekProbs {
+		cp *= p
+		allOut := 1.
+		for _, curve := range o.opponents {
+			allOut *= 1 - survival(curve, week)
+		}
+		value += (allOut - prevAllOut) * cp
+		prevAllOut = allOut
+	}
+	return Score{Value: value, Tiebreak: cp}
+}
+
+// MakeObjective returns the objective with the given name.
+// The names are "survival", "weeks", "minweek", and "outlast".
+// The outlast objective requires the survival curves of at least one opponent.
+func MakeObjective(name string, opponents [][]float64) (Objective, error) {
+	switch name {
+	case "", "survival":
+		return MaxSurvival{}, nil
+	case "weeks":
+		return ExpectedWeeks{}, nil
+	case "minweek":
+		return MaxMinWeek{}, nil
+	case "outlast":
+		if len(opponents) == 0 {
+			return nil, fmt.Errorf("objective %s requires at least one opponent", name)
+		}
+		return NewOutlast(opponents...), nil
+	default:
+		return nil, fmt.Errorf("unknown objective %s", name)
+	}
+}
@@ -0,0 +1,117 @@
+package bts
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestObjectives(t *testing.T) {
+	weekProbs := []float64{.9, .5, 1, .8}
+	tests := []struct {
+		name string
+		obj  Objective
+		want Score
+	}{
+		{"survival", MaxSurvival{}, Score{Value: .36, Tiebreak: 7}},
+		{"weeks", ExpectedWeeks{}, Score{Value: .9 + .45 + .45 + .36, Tiebreak: 7}},
+		{"minweek", MaxMinWeek{}, Score{Value: .5, Tiebreak: .36}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.obj.Score(weekProbs, 7)
+			if math.Abs(got.Value-tt.want.Value) > 1e-12 || math.Abs(got.Tiebreak-tt.want.Tiebreak) > 1e-12 {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := MakeObjective("outlast", nil); err == nil {
+		t.Errorf("expected error for outlast objective without opponents")
+	}
+	if _, err := MakeObjective("bogus", nil); err == nil {
+		t.Errorf("expected error for unknown objective")
+	}
+}
+
+func TestOutlast(t *testing.T) {
+	weekProbs := []float64{.9, .7, .8, .6}
+	opponents := [][]float64{{.8, .5, .4, .1}, {.95, .6}}
+	got := NewOutlast(opponents...).Score(weekProbs, 0).Value
+
+	// Simulate independent survival
+	rng := rand.New(rand.NewSource(0))
+	weeksSurvived := func(curve []float64) int {
+		for week := 0; week < len(weekProbs); week++ {
+			prev := 1.
+			if week > 0 {
+				prev = survival(curve, week-1)
+			}
+			cond := 0.
+			if prev > 0 {
+				cond = survival(curve, week) / prev
+			}
+			if rng.Float64() >= cond {
+				return week
+			}
+		}
+		return len(weekProbs)
+	}
+	n := 200000
+	wins := 0
+	player := make([]float64, len(weekProbs))
+	cp := 1.
+	for i, p := range weekProbs {
+		cp *= p
+		player[i] = cp
+	}
+	for i := 0; i < n; i++ {
+		mine := weeksSurvived(player)
+		best := 0
+		for _, curve := range opponents {
+			if w := weeksSurvived(curve); w > best {
+				best = w
+			}
+		}
+		if mine > best {
+			wins++
+		}
+	}
+	want := float64(wins) / float64(n)
+	if math.Abs(got-want) > .005 {
+		t.Errorf("expected approximately %f, got %f", want, got)
+	}
+}
+
+func TestSearchObjective(t *testing.T) {
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}, Team{"D"}}
+	p, err := NewPlayer("P", rem, []int{1, 2, 1}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pred := randomPredictions(TeamList(rem), 4, 4)
+
+	for _, obj := range []Objective{ExpectedWeeks{}, MaxMinWeek{}, NewOutlast([]float64{.5, .3, .1, .05})} {
+		best := Score{Value: math.Inf(-1)}
+		ctx := context.Background()
+		for wt := range p.WeekTypeIterator(ctx) {
+			for order := range p.RemainingIterator(ctx) {
+				s := NewStreak(rem, wt)
+				s.PermuteTeamOrder(order)
+				wp, spread := WeekProbabilities(pred, s, nil)
+				if score := obj.Score(wp, spread); score.Better(best) {
+					best = score
+				}
+			}
+		}
+
+		top, err := SearchShards(ctx, p, pred, obj, 3, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := top.Results()[0].Score; math.Abs(got.Value-best.Value) > 1e-12 {
+			t.Errorf("%T: expected best score %v, got %v", obj, best, got)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package bts
 
 import (
+	"context"
+	"fmt"
 	"math/big"
+	"math/rand"
 
 	"github.com/segmentio/fasthash/jody"
 )
@@ -87,13 +90,16 @@ func clone(x []int) []int {
 }
 
 // Iterator returns a channel-backed iterator that produces iterations of the identical sets represented by an IdenticalPermutor.
-// The channel closes once all the permutations have been pushed.
+// The channel closes once all the permutations have been pushed or once the context is cancelled, whichever comes first.
+// Consumers that stop reading early must cancel the context so the producing goroutine can exit.
 // The implementation uses Heap's algorithm (non-recursive) and a map of hashes to keep track of which permutations have been seen already.
 // This means for very large permutations, there is some small probability that a hash collision will occur and certain permutations could be skipped in the iteration.
-func (ip *IdenticalPermutor) Iterator() <-chan []int {
+func (ip *IdenticalPermutor) Iterator(ctx context.Context) <-chan []int {
 	ch := make(chan []int, 20)
 
 	go func() {
+		defer close(ch)
+
 		visited := make(map[uint64]bool)
 		out := make([]int, ip.Len())
 		counter := make([]int, len(out))
@@ -101,7 +107,9 @@ func (ip *IdenticalPermutor) Iterator() <-chan []int {
 		copy(out, ip.indices)
 
 		visited[hash(out)] = true
-		ch <- clone(out)
+		if !send(ctx, ch, clone(out)) {
+			return
+		}
 
 		i := 0
 		for i < len(out) {
@@ -114,7 +122,9 @@ func (ip *IdenticalPermutor) Iterator() <-chan []int {
 
 				if _, ok := visited[hash(out)]; !ok {
 					visited[hash(out)] = true
-					ch <- clone(out)
+					if !send(ctx, ch, clone(out)) {
+						return
+					}
 				}
 
 				counter[i]++
@@ -125,25 +135,29 @@ func (ip *IdenticalPermutor) Iterator() <-chan []int {
 				i++
 			}
 		}
-		close(ch)
 	}()
 
 	return ch
 }
 
-// Iterator returns a channel-backed iterator that produces iterations of the identical sets represented by an IdenticalPermutor.
-// The channel closes once all the permutations have been pushed.
+// Iterator returns a channel-backed iterator that produces iterations of the index range represented by an IndexPermutor.
+// The channel closes once all the permutations have been pushed or once the context is cancelled, whichever comes first.
+// Consumers that stop reading early must cancel the context so the producing goroutine can exit.
 // The implementation uses Heap's algorithm (non-recursive).
-func (ip *IndexPermutor) Iterator() <-chan []int {
+func (ip *IndexPermutor) Iterator(ctx context.Context) <-chan []int {
 	ch := make(chan []int, 20)
 
 	go func() {
+		defer close(ch)
+
 		out := make([]int, ip.Len())
 		counter := make([]int, len(out))
 
 		copy(out, ip.indices)
 
-		ch <- clone(out)
+		if !send(ctx, ch, clone(out)) {
+			return
+		}
 
 		i := 0
 		for i < len(out) {
@@ -154,7 +168,9 @@ func (ip *IndexPermutor) Iterator() <-chan []int {
 					out[counter[i]], out[i] = out[i], out[counter[i]]
 				}
 
-				ch <- clone(out)
+				if !send(ctx, ch, clone(out)) {
+					return
+				}
 
 				counter[i]++
 				i = 0
@@ -164,8 +180,146 @@ func (ip *IndexPermutor) Iterator() <-chan []int {
 				i++
 			}
 		}
-		close(ch)
 	}()
 
 	return ch
 }
+
+// send pushes a value to the channel unless the context is cancelled first.
+// It reports whether the value was sent.
+func send(ctx context.Context, ch chan<- []int, v []int) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Permutation returns the permutation of the given rank, where permutations are ranked in lexicographic order starting from zero.
+// The rank must be in the range [0, NumberOfPermutations()).
+// Note that the lexicographic order is not the order in which Iterator produces permutations.
+func (ip IndexPermutor) Permutation(rank *big.Int) ([]int, error) {
+	if rank.Sign() < 0 || rank.Cmp(ip.NumberOfPermutations()) >= 0 {
+		return nil, fmt.Errorf("rank %v out of range [0,%v)", rank, ip.NumberOfPermutations())
+	}
+
+	avail := clone(ip.indices)
+	out := make([]int, 0, len(avail))
+	r := new(big.Int).Set(rank)
+	q := new(big.Int)
+	for i := len(avail) - 1; i >= 0; i-- {
+		q.DivMod(r, factorial(i), r)
+		j := int(q.Int64())
+		out = append(out, avail[j])
+		avail = append(avail[:j], avail[j+1:]...)
+	}
+	return out, nil
+}
+
+// Rank returns the lexicographic rank of the given permutation.
+// This is the inverse of Permutation.
+func (ip IndexPermutor) Rank(perm []int) (*big.Int, error) {
+	if len(perm) != ip.Len() {
+		return nil, fmt.Errorf("permutation length %d does not match permutor length %d", len(perm), ip.Len())
+	}
+
+	used := make([]bool, ip.Len())
+	rank := new(big.Int)
+	term := new(big.Int)
+	for i, x := range perm {
+		if x < 0 || x >= ip.Len() || used[x] {
+			return nil, fmt.Errorf("%v is not a permutation of [0,%d)", perm, ip.Len())
+		}
+		smaller := 0
+		for y := 0; y < x; y++ {
+			if !used[y] {
+				smaller++
+			}
+		}
+		used[x] = true
+		term.Mul(big.NewInt(int64(smaller)), factorial(len(perm)-1-i))
+		rank.Add(rank, term)
+	}
+	return rank, nil
+}
+
+// Random returns a permutation drawn uniformly from all possible permutations.
+func (ip IndexPermutor) Random(rng *rand.Rand) []int {
+	perm, _ := ip.Permutation(new(big.Int).Rand(rng, ip.NumberOfPermutations()))
+	return perm
+}
+
+// multinomial calculates the number of distinct permutations of a set with the given numbers of identical copies of each element.
+func multinomial(counts []int) *big.Int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	out := factorial(n)
+	for _, c := range counts {
+		out.Div(out, factorial(c))
+	}
+	return out
+}
+
+// Permutation returns the permutation of the given rank, where distinct permutations are ranked in lexicographic order starting from zero.
+// The rank must be in the range [0, NumberOfPermutations()).
+// Note that the lexicographic order is not the order in which Iterator produces permutations.
+func (ip IdenticalPermutor) Permutation(rank *big.Int) ([]int, error) {
+	if rank.Sign() < 0 || rank.Cmp(ip.NumberOfPermutations()) >= 0 {
+		return nil, fmt.Errorf("rank %v out of range [0,%v)", rank, ip.NumberOfPermutations())
+	}
+
+	counts := clone(ip.sets)
+	out := make([]int, 0, ip.Len())
+	r := new(big.Int).Set(rank)
+	for len(out) < ip.Len() {
+		for v := range counts {
+			if counts[v] == 0 {
+				continue
+			}
+			counts[v]--
+			n := multinomial(counts)
+			if r.Cmp(n) < 0 {
+				out = append(out, v)
+				break
+			}
+			r.Sub(r, n)
+			counts[v]++
+		}
+	}
+	return out, nil
+}
+
+// Rank returns the lexicographic rank of the given permutation among all distinct permutations.
+// This is the inverse of Permutation.
+func (ip IdenticalPermutor) Rank(perm []int) (*big.Int, error) {
+	if len(perm) != ip.Len() {
+		return nil, fmt.Errorf("permutation length %d does not match permutor length %d", len(perm), ip.Len())
+	}
+
+	counts := clone(ip.sets)
+	rank := new(big.Int)
+	for _, x := range perm {
+		if x < 0 || x >= len(counts) || counts[x] == 0 {
+			return nil, fmt.Errorf("%v is not a permutation of %v", perm, ip.indices)
+		}
+		for v := 0; v < x; v++ {
+			if counts[v] == 0 {
+				continue
+			}
+			counts[v]--
+			rank.Add(rank, multinomial(counts))
+			counts[v]++
+		}
+		counts[x]--
+	}
+	return rank, nil
+}
+
+// Random returns a distinct permutation drawn uniformly from all possible distinct permutations.
+func (ip IdenticalPermutor) Random(rng *rand.Rand) []int {
+	perm, _ := ip.Permutation(new(big.Int).Rand(rng, ip.NumberOfPermutations()))
+	return perm
+}
@@ -1,6 +1,7 @@
 package bts
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"sort"
@@ -12,9 +13,10 @@ import (
 
 // Player represents a player's current status in the competition.
 type Player struct {
-	name      string
-	remaining Remaining
-	weekTypes *IdenticalPermutor
+	name         string
+	remaining    Remaining
+	weekTypes    *IdenticalPermutor
+	availability Availability
 }
 
 // Name returns the player's name
@@ -42,13 +44,98 @@ func (p Player) RemainingWeeks() int {
 }
 
 // RemainingIterator returns an iterator over remaining team indices.
-func (p Player) RemainingIterator() <-chan []int {
-	return NewIndexPermutor(len(p.remaining)).Iterator()
+// Cancel the context to stop the iteration early.
+func (p Player) RemainingIterator(ctx context.Context) <-chan []int {
+	return NewIndexPermutor(len(p.remaining)).Iterator(ctx)
 }
 
 // WeekTypeIterator returns an iterator over remaining week types.
-func (p Player) WeekTypeIterator() <-chan []int {
-	return p.weekTypes.Iterator()
+// Cancel the context to stop the iteration early.
+func (p Player) WeekTypeIterator(ctx context.Context) <-chan []int {
+	return p.weekTypes.Iterator(ctx)
+}
+
+// Constrain restricts the weeks in which the player may pick each remaining team.
+// Teams are never allowed to be picked in weeks they have a bye in the schedule, in addition to the given constraints.
+// The schedule is expected to be filtered so that its first week is the season week firstWeek.
+func (p *Player) Constrain(s *Schedule, constraints []PickConstraint, firstWeek int) error {
+	a, err := NewAvailability(p.remaining, s, constraints, firstWeek)
+	if err != nil {
+		return fmt.Errorf("player %s: %v", p.name, err)
+	}
+	p.availability = a
+	return nil
+}
+
+// ApplyRules prevents the player from picking teams in weeks in which the pick would be refunded under the pool rules.
+// The schedule is expected to be filtered to the same weeks as it was for Constrain, which must be called first because it replaces the player's availability.
+func (p *Player) ApplyRules(s *Schedule, rules PoolRules) error {
+	if p.availability == nil {
+		a, err := NewAvailability(p.remaining, s, nil, 0)
+		if err != nil {
+			return fmt.Errorf("player %s: %v", p.name, err)
+		}
+		p.availability = a
+	}
+	for team, weeks := range p.availability {
+		for week := range weeks {
+			if rules.Refunded(s, team, week) {
+				weeks[week] = false
+			}
+		}
+	}
+	return nil
+}
+
+// Availability returns the weeks in which the player may pick each remaining team.
+// A nil Availability means the player is unconstrained.
+func (p Player) Availability() Availability {
+	return p.availability
+}
+
+// FeasibleStreak returns a streak in which every remaining team is picked in a week it is allowed to be picked.
+// An error is returned if no such streak exists.
+func (p Player) FeasibleStreak(ctx context.Context) (*Streak, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for wt := range p.WeekTypeIterator(ctx) {
+		if order, ok := p.availability.assign(p.remaining, wt); ok {
+			return NewStreak(Remaining(order), wt), nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("player %s has no streak that satisfies all pick constraints", p.name)
+}
+
+// Alive reports whether the player can still survive every remaining week: whether some streak satisfies the player's pick constraints
+// and picks only teams with a chance of winning in the weeks they are picked.
+// Predictions should be made with the pool rules (see PoolRules.Model) so that picks scored as losses are ruled out.
+func (p Player) Alive(ctx context.Context, predictions *Predictions) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	winnable := make(Availability, len(p.remaining))
+	nWeeks := p.RemainingWeeks()
+	for _, team := range p.remaining {
+		if team == NONE {
+			continue
+		}
+		weeks := make([]bool, nWeeks)
+		for week := range weeks {
+			weeks[week] = p.availability.Allows(team, week) && predictions.GetProbability(team, week) > 0
+		}
+		winnable[team] = weeks
+	}
+
+	for wt := range p.WeekTypeIterator(ctx) {
+		if _, ok := winnable.assign(p.remaining, wt); ok {
+			return true, nil
+		}
+	}
+	return false, ctx.Err()
 }
 
 // Remaining represents a player's teams remaining.
@@ -63,16 +150,26 @@ type WeeksMap map[string][]int
 // PlayerMap associates a player's name with a status.
 type PlayerMap map[string]*Player
 
-// NewPlayer builds a new player
-func NewPlayer(name string, remaining Remaining, weekTypesRemaining []int) (*Player, error) {
+// NewPlayer builds a new player.
+// The ith value of weekTypesRemaining is the number of weeks remaining in which the player makes i picks, so weeks with any number of picks (including zero) are supported.
+// The number of weeks remaining in the schedule, nWeeks, is used to reject week types that describe more weeks than are left to play.
+func NewPlayer(name string, remaining Remaining, weekTypesRemaining []int, nWeeks int) (*Player, error) {
 	nTeams := len(remaining)
 	nPicks := 0
+	nPickWeeks := 0
 	for itype, ntype := range weekTypesRemaining {
+		if ntype < 0 {
+			return nil, fmt.Errorf("number of weeks with %d picks (%d) must not be negative", itype, ntype)
+		}
 		nPicks += itype * ntype
+		nPickWeeks += ntype
 	}
 	if nPicks != nTeams {
 		return nil, fmt.Errorf("number of teams remaining (%d) must equal number of picks remaining (%d)", nTeams, nPicks)
 	}
+	if nPickWeeks > nWeeks {
+		return nil, fmt.Errorf("number of weeks of picks remaining (%d) must not exceed number of weeks remaining in the schedule (%d)", nPickWeeks, nWeeks)
+	}
 	return &Player{
 		name:      name,
 		remaining: remaining,
@@ -81,7 +178,8 @@ func NewPlayer(name string, remaining Remaining, weekTypesRemaining []int) (*Pla
 }
 
 // MakePlayers parses a YAML file and produces a map of remaining players.
-func MakePlayers(playerFile string, weekTypeFile string) (PlayerMap, error) {
+// The number of weeks remaining in the schedule, nWeeks, is used to validate the players' week types.
+func MakePlayers(playerFile string, weekTypeFile string, nWeeks int) (PlayerMap, error) {
 	playerYaml, err := ioutil.ReadFile(playerFile)
 	if err != nil {
 		return nil, err
@@ -119,7 +217,7 @@ func MakePlayers(playerFile string, weekTypeFile string) (PlayerMap, error) {
 	pm := make(PlayerMap)
 	for p, r := range rm {
 		var err error
-		pm[p], err = NewPlayer(p, r, wm[p])
+		pm[p], err = NewPlayer(p, r, wm[p], nWeeks)
 		if err != nil {
 			return nil, err
 		}
@@ -141,6 +239,18 @@ func (pm PlayerMap) Duplicates() map[string][]string {
 		for _, weektype := range player.weekTypes.sets {
 			hash = jody.AddUint64(hash, uint64(weektype))
 		}
+		// Players with the same teams but different constraints are not duplicates.
+		if player.availability != nil {
+			for _, team := range player.remaining {
+				for _, allowed := range player.availability[team] {
+					if allowed {
+						hash = jody.AddUint64(hash, 1)
+					} else {
+						hash = jody.AddUint64(hash, 0)
+					}
+				}
+			}
+		}
 		playerHashes[hash] = append(playerHashes[hash], name)
 	}
 
@@ -1,6 +1,10 @@
 package bts
 
-import "testing"
+import (
+	"context"
+	"runtime"
+	"testing"
+)
 
 func TestDuplicates(t *testing.T) {
 	pm := make(PlayerMap)
@@ -12,3 +16,49 @@ func TestDuplicates(t *testing.T) {
 
 	pm.Duplicates()
 }
+
+func TestWeekTypeIteratorCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	p, err := NewPlayer("A", Remaining{Team{"AAA"}, Team{"BBB"}, Team{"CCC"}, Team{"DDD"}}, []int{2, 2, 1}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		<-p.WeekTypeIterator(ctx)
+		<-p.RemainingIterator(ctx)
+		cancel()
+	}
+
+	if n := leaked(before); n > 0 {
+		t.Errorf("expected no leaked goroutines, got %d", n)
+	}
+}
+
+func TestNewPlayer(t *testing.T) {
+	rem := Remaining{Team{"AAA"}, Team{"BBB"}, Team{"CCC"}, Team{"DDD"}, Team{"EEE"}}
+	tests := []struct {
+		name      string
+		weekTypes []int
+		nWeeks    int
+		wantErr   bool
+	}{
+		{"singles", []int{0, 5}, 5, false},
+		{"singles with byes", []int{2, 5}, 7, false},
+		{"triple", []int{0, 2, 0, 1}, 3, false},
+		{"quadruple and bye", []int{1, 1, 0, 0, 1}, 5, false},
+		{"too few picks", []int{0, 4}, 5, true},
+		{"too many picks", []int{0, 1, 0, 2}, 5, true},
+		{"longer than schedule", []int{3, 5}, 7, true},
+		{"negative weeks", []int{-1, 3, 1}, 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPlayer("A", rem, tt.weekTypes, tt.nWeeks)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPlayer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
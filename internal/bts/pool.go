@@ -0,0 +1,142 @@
+package bts
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// poolGame identifies a game in a pool simulation: the teams that play it, in name order, the week,
+// and how many games the same teams have already played against each other that week.
+// Games not played as scheduled are scored by the pool rules for each team separately, so they are also identified by the picked team.
+type poolGame struct {
+	teams [2]Team
+	week  int
+	n     int
+	side  Team
+}
+
+// poolDraw is the probability that a team wins a game in a pool simulation.
+type poolDraw struct {
+	team Team
+	prob float64
+}
+
+// poolPick is a pick on a game in a pool simulation, which wins if the outcome drawn for the game is won (or lost, if flipped).
+type poolPick struct {
+	draw int
+	flip bool
+}
+
+// SimulatePool estimates the probability that each player in a pool is the last survivor, given the streak each player will follow.
+// Each iteration samples the outcome of every picked game once, so players who pick the same team in the same week win or lose together,
+// and players who pick opposite sides of the same game cannot both win it.
+// A player survives until the first week in which any of their picks lose.
+// Picks on games that are not played as scheduled are scored by the pool rules if the model applies them (see PoolRules.Model).
+// The player who survives the most weeks wins the pool; players tied for the most weeks survived split the win evenly.
+// The search stops early with the context's error if the context is cancelled.
+func SimulatePool(ctx context.Context, rng *rand.Rand, schedule *Schedule, model PredictionModel, streaks map[string]*Streak, iterations int) (map[string]float64, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("pool simulation requires at least one iteration, got %d", iterations)
+	}
+
+	// Sort players so outcomes are drawn in the same order for the same seed.
+	names := make([]string, 0, len(streaks))
+	for name := range streaks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Index every distinct game so its outcome can be drawn once per iteration.
+	index := make(map[poolGame]int)
+	draws := make([]poolDraw, 0)
+	picks := make([][][]poolPick, len(names))
+	for i, name := range names {
+		s := streaks[name]
+		picks[i] = make([][]poolPick, s.NumWeeks())
+		for week := range picks[i] {
+			for _, team := range s.GetWeek(week) {
+				if team == NONE {
+					continue
+				}
+				games := schedule.Games(team, week)
+				if len(games) == 0 {
+					// Picks on bye weeks always lose.
+					games = []*Game{NewGame(team, BYE, Neutral)}
+				}
+				met := make(map[Team]int)
+				for _, g := range games {
+					opponent := g.Team(1)
+					pg := poolGame{teams: [2]Team{team, opponent}, week: week, n: met[opponent]}
+					met[opponent]++
+					if opponent.Name() < team.Name() {
+						pg.teams = [2]Team{opponent, team}
+					}
+					if g.Status() != Scheduled || opponent == BYE {
+						pg.side = team
+					}
+					j, ok := index[pg]
+					if !ok {
+						j = len(draws)
+						index[pg] = j
+						p, _ := model.Predict(g)
+						draws = append(draws, poolDraw{team: team, prob: p})
+					}
+					picks[i][week] = append(picks[i][week], poolPick{draw: j, flip: draws[j].team != team})
+				}
+			}
+		}
+	}
+
+	wins := make([]float64, len(names))
+	won := make([]bool, len(draws))
+	survived := make([]int, len(names))
+	for itr := 0; itr < iterations; itr++ {
+		if itr%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		for j, d := range draws {
+			won[j] = rng.Float64() < d.prob
+		}
+
+		best := -1
+		nBest := 0
+		for i := range names {
+			survived[i] = weeksSurvived(picks[i], won)
+			switch {
+			case survived[i] > best:
+				best = survived[i]
+				nBest = 1
+			case survived[i] == best:
+				nBest++
+			}
+		}
+		for i := range names {
+			if survived[i] == best {
+				wins[i] += 1. / float64(nBest)
+			}
+		}
+	}
+
+	out := make(map[string]float64, len(names))
+	for i, name := range names {
+		out[name] = wins[i] / float64(iterations)
+	}
+	return out, nil
+}
+
+// weeksSurvived counts the weeks survived before the first week with a losing pick.
+func weeksSurvived(picks [][]poolPick, won []bool) int {
+	for week, weekPicks := range picks {
+		for _, pp := range weekPicks {
+			if won[pp.draw] == pp.flip {
+				return week
+			}
+		}
+	}
+	return len(picks)
+}
@@ -0,0 +1,77 @@
+package bts
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSimulatePool(t *testing.T) {
+	// B and E are evenly matched in the first week; every other game is a sure thing.
+	s := ScheduleFromNotation(map[string][]string{
+		"A": {"!Z", "!Z", "!Z"},
+		"B": {"!E", "!Z", "!Z"},
+		"C": {"!Z", "!Z", "!Z"},
+		"E": {"!B", "!Z", "!Z"},
+	})
+	model := NewGaussianSpreadModel(map[Team]float64{{"A"}: 100, {"B"}: 0, {"C"}: 100, {"E"}: 0, {"Z"}: -100}, 10, 0, 0)
+
+	ppw := []int{1, 1, 1}
+	safe := NewStreak(Remaining{Team{"A"}, Team{"C"}, Team{"B"}}, ppw)
+	risky := NewStreak(Remaining{Team{"B"}, Team{"A"}, Team{"C"}}, ppw)
+
+	streaks := map[string]*Streak{"safe": safe, "risky": risky}
+	got, err := SimulatePool(context.Background(), rand.New(rand.NewSource(0)), &s, model, streaks, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The risky player loses in the first week half of the time, otherwise both players tie.
+	if math.Abs(got["safe"]-.75) > .01 || math.Abs(got["risky"]-.25) > .01 {
+		t.Errorf("expected win probabilities near .75 and .25, got %v", got)
+	}
+	if math.Abs(got["safe"]+got["risky"]-1) > 1e-9 {
+		t.Errorf("expected win probabilities to sum to 1, got %v", got)
+	}
+
+	// Players with the same picks share outcomes, so they always tie.
+	streaks = map[string]*Streak{"risky": risky, "clone": risky.Clone()}
+	got, err = SimulatePool(context.Background(), rand.New(rand.NewSource(0)), &s, model, streaks, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["risky"] != .5 || got["clone"] != .5 {
+		t.Errorf("expected identical streaks to split every win, got %v", got)
+	}
+
+	// Players on opposite sides of the same game never both win it, so exactly one of them ties the safe player.
+	contrarian := NewStreak(Remaining{Team{"E"}, Team{"A"}, Team{"C"}}, ppw)
+	streaks = map[string]*Streak{"safe": safe, "risky": risky, "contrarian": contrarian}
+	got, err = SimulatePool(context.Background(), rand.New(rand.NewSource(0)), &s, model, streaks, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got["safe"]-.5) > .01 || math.Abs(got["risky"]-.25) > .01 || math.Abs(got["contrarian"]-.25) > .01 {
+		t.Errorf("expected win probabilities near .5, .25, and .25, got %v", got)
+	}
+
+	// Weeks without picks are always survived.
+	none := NewStreak(Remaining{Team{"A"}, NONE, Team{"C"}}, ppw)
+	streaks = map[string]*Streak{"none": none, "risky": risky}
+	got, err = SimulatePool(context.Background(), rand.New(rand.NewSource(0)), &s, model, streaks, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["none"] <= got["risky"] {
+		t.Errorf("expected player without risky picks to be favored, got %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SimulatePool(ctx, rand.New(rand.NewSource(0)), &s, model, streaks, 1000); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, err := SimulatePool(context.Background(), rand.New(rand.NewSource(0)), &s, model, streaks, 0); err == nil {
+		t.Errorf("expected error for zero iterations")
+	}
+}
@@ -92,7 +92,85 @@ func SummarizeStreak(p *Predictions, s *Streak) (prob, spread float64) {
 	return
 }
 
+// SurvivalSummary describes how long a streak is expected to survive.
+type SurvivalSummary struct {
+	// Curve is the probability of surviving through each week of the streak.
+	Curve []float64
+	// ExpectedWeeks is the expected number of weeks survived before the first loss.
+	ExpectedWeeks float64
+	// LargestDropWeek is the week of the streak in which the probability of survival drops the most.
+	LargestDropWeek int
+	// LargestDrop is how much the probability of survival drops in LargestDropWeek.
+	LargestDrop float64
+}
+
+// SummarizeSurvival calculates the survival curve of a streak and summarizes it.
+func SummarizeSurvival(p *Predictions, s *Streak) SurvivalSummary {
+	weekProbs := make([]float64, s.NumWeeks())
+	for week := range weekProbs {
+		weekProbs[week] = 1.
+		for _, pick := range s.GetWeek(week) {
+			weekProbs[week] *= p.GetProbability(pick, week)
+		}
+	}
+	return SummarizeWeeks(weekProbs)
+}
+
+// SummarizeWeeks calculates the survival curve of a streak from the probability of winning each of its weeks and summarizes it.
+// Weeks without picks have a probability of 1.
+func SummarizeWeeks(weekProbs []float64) SurvivalSummary {
+	summary := SurvivalSummary{Curve: make([]float64, len(weekProbs))}
+
+	cp := 1.
+	prev := 1.
+	for week, p := range weekProbs {
+		cp *= p
+		summary.Curve[week] = cp
+		summary.ExpectedWeeks += cp
+		if drop := prev - cp; drop > summary.LargestDrop {
+			summary.LargestDrop = drop
+			summary.LargestDropWeek = week
+		}
+		prev = cp
+	}
+
+	return summary
+}
+
+// PickProbabilities are the probabilities of winning each pick made in a given season week.
+type PickProbabilities struct {
+	Week          int
+	Probabilities []float64
+}
+
+// PickedWeekProbabilities calculates the probability of winning every pick in each week of a stored streak, starting with the given season week.
+// Weeks before the first week are ignored, and weeks without picks have a probability of 1.
+func PickedWeekProbabilities(weeks []PickProbabilities, firstWeek int) []float64 {
+	n := 0
+	for _, week := range weeks {
+		if week.Week-firstWeek+1 > n {
+			n = week.Week - firstWeek + 1
+		}
+	}
+	weekProbs := make([]float64, n)
+	for i := range weekProbs {
+		weekProbs[i] = 1.
+	}
+	for _, week := range weeks {
+		i := week.Week - firstWeek
+		if i < 0 {
+			continue
+		}
+		for _, p := range week.Probabilities {
+			weekProbs[i] *= p
+		}
+	}
+	return weekProbs
+}
+
 // MakePredictions uses a schedule and a model to build a map of predictions for fast lookup.
+// A pick must win every game its team plays in a week, so the probability of a week is the product of the probabilities of winning each game,
+// and the spread is the sum of the spreads. Weeks without games (byes) have a probability and spread of zero.
 func MakePredictions(s *Schedule, m PredictionModel) *Predictions {
 	tl := s.TeamList()
 	nWeeks := s.NumWeeks()
@@ -104,13 +182,27 @@ func MakePredictions(s *Schedule, m PredictionModel) *Predictions {
 		probs[t1] = make([]float64, nWeeks)
 		spreads[t1] = make([]float64, nWeeks)
 		for week := 0; week < nWeeks; week++ {
-			probs[t1][week], spreads[t1][week] = m.Predict(s.Get(t1, week))
+			probs[t1][week], spreads[t1][week] = predictWeek(m, s.Games(t1, week))
 		}
 	}
 
 	return &Predictions{probs: probs, spreads: spreads}
 }
 
+// predictWeek predicts the probability of winning every one of a week's games and the total spread of those games.
+func predictWeek(m PredictionModel, games []*Game) (float64, float64) {
+	if len(games) == 0 {
+		return 0., 0.
+	}
+	prob, spread := 1., 0.
+	for _, g := range games {
+		p, s := m.Predict(g)
+		prob *= p
+		spread += s
+	}
+	return prob, spread
+}
+
 func (p Predictions) String() string {
 	keys := make([]string, len(p.probs))
 	i := 0
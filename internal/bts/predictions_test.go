@@ -1,6 +1,9 @@
 package bts
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestString(t *testing.T) {
 	p := EmptyPredictions(TeamList{
@@ -12,3 +15,50 @@ func TestString(t *testing.T) {
 		Team{"Fish U"}}, 14)
 	t.Logf("\n%s", p.String())
 }
+
+func TestSummarizeSurvival(t *testing.T) {
+	tl := TeamList{Team{"A"}, Team{"B"}, Team{"C"}}
+	p := EmptyPredictions(tl, 3)
+	p.probs[Team{"A"}] = []float64{.9, .9, .9}
+	p.probs[Team{"B"}] = []float64{.5, .5, .5}
+	p.probs[Team{"C"}] = []float64{.8, .8, .8}
+
+	s := NewStreak(Remaining{Team{"A"}, Team{"B"}, Team{"C"}}, []int{1, 1, 1})
+	summary := SummarizeSurvival(p, s)
+
+	curve := []float64{.9, .45, .36}
+	for week, cp := range curve {
+		if math.Abs(summary.Curve[week]-cp) > 1e-12 {
+			t.Errorf("week %d: expected survival %f, got %f", week, cp, summary.Curve[week])
+		}
+	}
+	if math.Abs(summary.ExpectedWeeks-(.9+.45+.36)) > 1e-12 {
+		t.Errorf("expected %f weeks, got %f", .9+.45+.36, summary.ExpectedWeeks)
+	}
+	if summary.LargestDropWeek != 1 || math.Abs(summary.LargestDrop-.45) > 1e-12 {
+		t.Errorf("expected largest drop of %f in week 1, got %f in week %d", .45, summary.LargestDrop, summary.LargestDropWeek)
+	}
+}
+
+func TestPickedWeekProbabilities(t *testing.T) {
+	weeks := []PickProbabilities{
+		{Week: 1, Probabilities: []float64{.5}},
+		{Week: 3, Probabilities: []float64{.9, .8}},
+		{Week: 4, Probabilities: []float64{}},
+		{Week: 6, Probabilities: []float64{.7}},
+	}
+	expected := []float64{.72, 1, 1, .7}
+	weekProbs := PickedWeekProbabilities(weeks, 3)
+	if len(weekProbs) != len(expected) {
+		t.Fatalf("expected %d weeks, got %d", len(expected), len(weekProbs))
+	}
+	for i, p := range expected {
+		if math.Abs(weekProbs[i]-p) > 1e-12 {
+			t.Errorf("week %d: expected probability %f, got %f", i+3, p, weekProbs[i])
+		}
+	}
+
+	if weekProbs := PickedWeekProbabilities(weeks, 7); len(weekProbs) != 0 {
+		t.Errorf("expected no weeks after the last pick, got %v", weekProbs)
+	}
+}
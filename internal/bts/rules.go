@@ -0,0 +1,159 @@
+package bts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PickRule is how a pool scores a pick on a game that is not played as scheduled.
+type PickRule int
+
+const (
+	// Refund returns the pick to the player as though it had not been made.
+	Refund PickRule = iota
+
+	// CountAsWin scores the pick as a win.
+	CountAsWin
+
+	// CountAsLoss scores the pick as a loss.
+	CountAsLoss
+)
+
+var pickRuleNames = map[PickRule]string{
+	Refund:      "refund",
+	CountAsWin:  "win",
+	CountAsLoss: "loss",
+}
+
+func (r PickRule) String() string {
+	if name, ok := pickRuleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("PickRule(%d)", int(r))
+}
+
+// ParsePickRule parses the name of a pick rule: "refund", "win", or "loss".
+func ParsePickRule(name string) (PickRule, error) {
+	for r, n := range pickRuleNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	return Refund, fmt.Errorf("unknown pick rule \"%s\"", name)
+}
+
+// PoolRules are a pool's rules for scoring picks on games that are not played as scheduled.
+// The zero value refunds every such pick.
+type PoolRules struct {
+	Postponed PickRule
+	Cancelled PickRule
+	NoContest PickRule
+}
+
+// ParsePoolRules parses pool rules from a comma-separated list of game statuses and pick rules, like "cancelled=win,no-contest=loss".
+// Statuses that are not listed are refunded.
+func ParsePoolRules(s string) (PoolRules, error) {
+	var rules PoolRules
+	if strings.TrimSpace(s) == "" {
+		return rules, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return rules, fmt.Errorf("pool rule \"%s\" is not of the form status=rule", field)
+		}
+		status, err := ParseGameStatus(strings.TrimSpace(kv[0]))
+		if err != nil {
+			return rules, err
+		}
+		rule, err := ParsePickRule(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return rules, err
+		}
+		switch status {
+		case Postponed:
+			rules.Postponed = rule
+		case Cancelled:
+			rules.Cancelled = rule
+		case NoContest:
+			rules.NoContest = rule
+		default:
+			return rules, fmt.Errorf("pool rules cannot apply to %s games", status)
+		}
+	}
+	return rules, nil
+}
+
+func (r PoolRules) String() string {
+	return fmt.Sprintf("%s=%s,%s=%s,%s=%s", Postponed, r.Postponed, Cancelled, r.Cancelled, NoContest, r.NoContest)
+}
+
+// Rule returns the rule for scoring a pick on a game, and whether one applies. Picks on scheduled games are scored by the result of the game.
+func (r PoolRules) Rule(g *Game) (PickRule, bool) {
+	switch g.Status() {
+	case Postponed:
+		return r.Postponed, true
+	case Cancelled:
+		return r.Cancelled, true
+	case NoContest:
+		return r.NoContest, true
+	default:
+		return Refund, false
+	}
+}
+
+// Refunded reports whether a pick of a team in a week would be refunded because every game the team plays that week is refunded.
+// Teams without games in the week are not refunded: they cannot be picked at all.
+func (r PoolRules) Refunded(s *Schedule, t Team, w int) bool {
+	games := s.Games(t, w)
+	if len(games) == 0 {
+		return false
+	}
+	for _, g := range games {
+		if rule, ok := r.Rule(g); !ok || rule != Refund {
+			return false
+		}
+	}
+	return true
+}
+
+// Model makes a prediction model that scores games that are not played as scheduled by the pool rules, and predicts every other game with m.
+// Games counted as wins are won with certainty, and games counted as losses are lost with certainty.
+// Refunded games do not count toward a pick, so the pick is decided by the team's other games that week.
+// Weeks in which every game is refunded should not be picked at all (see Player.ApplyRules).
+func (r PoolRules) Model(m PredictionModel) PredictionModel {
+	return rulesModel{rules: r, model: m}
+}
+
+// rulesModel implements PredictionModel by applying pool rules to another model.
+type rulesModel struct {
+	rules PoolRules
+	model PredictionModel
+}
+
+// Predict returns the probability of the pick on the first team being scored as a win, and the predicted spread.
+// Games scored by a rule have a spread of 0.
+func (m rulesModel) Predict(game *Game) (float64, float64) {
+	rule, ok := m.rules.Rule(game)
+	if !ok {
+		return m.model.Predict(game)
+	}
+	switch rule {
+	case CountAsLoss:
+		return 0., 0.
+	default:
+		return 1., 0.
+	}
+}
+
+// MostLikelyOutcome returns the team the pool rules score as the winner of the game, or the most likely winner if the game is played or refunded.
+func (m rulesModel) MostLikelyOutcome(game *Game) (Team, float64, float64) {
+	rule, ok := m.rules.Rule(game)
+	if !ok || rule == Refund {
+		return m.model.MostLikelyOutcome(game)
+	}
+	if rule == CountAsLoss {
+		return game.Team(1), 1., 0.
+	}
+	return game.Team(0), 1., 0.
+}
@@ -0,0 +1,136 @@
+package bts
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParsePoolRules(t *testing.T) {
+	rules, err := ParsePoolRules("cancelled=win, no-contest=loss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := PoolRules{Postponed: Refund, Cancelled: CountAsWin, NoContest: CountAsLoss}
+	if rules != expected {
+		t.Errorf("expected %v, got %v", expected, rules)
+	}
+	if parsed, err := ParsePoolRules(rules.String()); err != nil || parsed != rules {
+		t.Errorf("expected %v after round trip, got %v (%v)", rules, parsed, err)
+	}
+	if rules, err := ParsePoolRules(""); err != nil || rules != (PoolRules{}) {
+		t.Errorf("expected every pick refunded by default, got %v (%v)", rules, err)
+	}
+
+	for _, bad := range []string{"cancelled", "cancelled=tie", "forfeit=win", "scheduled=win"} {
+		if _, err := ParsePoolRules(bad); err == nil {
+			t.Errorf("expected error for \"%s\"", bad)
+		}
+	}
+}
+
+// testRulesSchedule has a postponed game in week 0, a cancelled game in week 1, and a no-contest in week 2 alongside a game played as scheduled.
+const testRulesSchedule = `AAA: [{opponent: "BBB", status: "postponed"}, {opponent: "@CCC", status: "cancelled"}, [{opponent: "BBB", status: "no-contest"}, "CCC"]]
+BBB: [{opponent: "@AAA", status: "postponed"}, "CCC", [{opponent: "@AAA", status: "no-contest"}]]
+CCC: ["", {opponent: "AAA", status: "cancelled"}, "@AAA"]
+`
+
+func TestPoolRulesModel(t *testing.T) {
+	s, err := ParseSchedule(strings.NewReader(testRulesSchedule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aaa, bbb, ccc := Team{"AAA"}, Team{"BBB"}, Team{"CCC"}
+	if status := s.Get(aaa, 1).Status(); status != Cancelled {
+		t.Errorf("expected AAA week 1 to be cancelled, got %s", status)
+	}
+	if w := s.Week(aaa, 2).String(); w != "BBB (no-contest) + CCC" {
+		t.Errorf("expected AAA week 2 to be \"BBB (no-contest) + CCC\", got \"%s\"", w)
+	}
+
+	model := NewGaussianSpreadModel(map[Team]float64{aaa: 0, bbb: 0, ccc: 0}, 10, 0, 0)
+	rules := PoolRules{Postponed: Refund, Cancelled: CountAsWin, NoContest: CountAsLoss}
+	p := MakePredictions(s, rules.Model(model))
+	tests := []struct {
+		team     Team
+		week     int
+		expected float64
+	}{
+		{aaa, 0, 1},
+		{aaa, 1, 1},
+		{ccc, 1, 1},
+		{bbb, 1, .5},
+		// A pick must win every game of the week, and the no-contest counts as a loss.
+		{aaa, 2, 0},
+		{bbb, 2, 0},
+	}
+	for _, test := range tests {
+		if prob := p.GetProbability(test.team, test.week); prob != test.expected {
+			t.Errorf("%s week %d: expected probability %f, got %f", test.team.Name(), test.week, test.expected, prob)
+		}
+	}
+
+	if !rules.Refunded(s, aaa, 0) || rules.Refunded(s, aaa, 1) || rules.Refunded(s, ccc, 0) {
+		t.Errorf("expected only the postponed game to be refunded")
+	}
+	refundAll := PoolRules{}
+	if refundAll.Refunded(s, aaa, 2) {
+		t.Errorf("expected a week with a game played as scheduled not to be refunded")
+	}
+}
+
+func TestPlayerRules(t *testing.T) {
+	s, err := ParseSchedule(strings.NewReader(testRulesSchedule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aaa, bbb, ccc := Team{"AAA"}, Team{"BBB"}, Team{"CCC"}
+	model := NewGaussianSpreadModel(map[Team]float64{aaa: 0, bbb: 0, ccc: 0}, 10, 0, 0)
+	ctx := context.Background()
+
+	rules := PoolRules{Postponed: Refund, Cancelled: CountAsWin, NoContest: CountAsLoss}
+	p, err := NewPlayer("Person 1", Remaining{aaa, bbb, ccc}, []int{0, 3}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Constrain(s, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ApplyRules(s, rules); err != nil {
+		t.Fatal(err)
+	}
+	if a := p.Availability(); a.Allows(aaa, 0) || a.Allows(bbb, 0) || !a.Allows(aaa, 1) {
+		t.Errorf("expected picks of the postponed game to be refunded, got %v", a)
+	}
+
+	// AAA and BBB cannot win week 2 and CCC has a bye in week 0, so the player cannot survive.
+	alive, err := p.Alive(ctx, MakePredictions(s, rules.Model(model)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alive {
+		t.Errorf("expected player to be eliminated under %v", rules)
+	}
+
+	// A player who can skip a week survives if the no-contest is refunded, so that AAA can win week 2 by beating CCC.
+	q, err := NewPlayer("Person 2", Remaining{aaa, bbb}, []int{1, 2}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range []PickRule{CountAsLoss, Refund} {
+		rules.NoContest = rule
+		if err := q.Constrain(s, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.ApplyRules(s, rules); err != nil {
+			t.Fatal(err)
+		}
+		alive, err := q.Alive(ctx, MakePredictions(s, rules.Model(model)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alive != (rule == Refund) {
+			t.Errorf("expected player alive to be %t under %v, got %t", rule == Refund, rules, alive)
+		}
+	}
+}
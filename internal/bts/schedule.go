@@ -2,55 +2,291 @@ package bts
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
-// Schedule is a team's schedule for the year.
-type Schedule map[Team][]*Game
+// Schedule is every team's schedule for the year: the games each team plays in each week.
+// A team may play any number of games in a week. A week without games is a bye.
+type Schedule map[Team][][]*Game
 
 // MakeSchedule parses a schedule YAML file.
 func MakeSchedule(fileName string) (*Schedule, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSchedule(f)
+}
 
-	schedYaml, err := ioutil.ReadFile(fileName)
+// ParseSchedule parses a schedule in the compact YAML notation, mapping each team to a list of opponents, one per week.
+// Opponents are prefixed with '@' (away), '>' (far), '<' (near), or '!' (neutral), or unprefixed for home games.
+// Empty strings and "BYE" are bye weeks, and weeks with more than one game join the opponents with '+', like "@AAA+BBB".
+// Any game can instead be written in long form as a mapping with the opponent and, optionally, the kickoff time, venue, status, and travel distances (see ScheduleEntry),
+// and any week as a list of games.
+func ParseSchedule(r io.Reader) (*Schedule, error) {
+	schedYaml, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	s := make(map[Team][]string)
+	s := make(map[string][]ScheduleWeek)
 	err = yaml.Unmarshal(schedYaml, s)
 	if err != nil {
 		return nil, err
 	}
 
-	// for k, v := range s {
-	// 	if len(v) != NGames {
-	// 		return nil, fmt.Errorf("schedule for team %s incorrect: expected %d, got %d", k, NGames, len(v))
-	// 	}
-	// }
+	sched := ScheduleFromWeeks(s)
+	return &sched, nil
+}
+
+// gameSeparator separates the games of a week in the compact notation.
+const gameSeparator = "+"
+
+// ScheduleWeek is the games a team plays in one week of a schedule in the YAML notation. Byes have no games.
+// In YAML, it is the compact notation of the opponents joined by '+', a single game in long form, or a list of games.
+type ScheduleWeek []ScheduleEntry
+
+// UnmarshalYAML parses any of the forms of a week.
+func (w *ScheduleWeek) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var notation string
+	if err := unmarshal(&notation); err == nil {
+		*w = ParseWeekNotation(notation)
+		return nil
+	}
+
+	var entries []ScheduleEntry
+	if err := unmarshal(&entries); err != nil {
+		var entry ScheduleEntry
+		if err := unmarshal(&entry); err != nil {
+			return err
+		}
+		entries = []ScheduleEntry{entry}
+	}
+	*w = make(ScheduleWeek, 0, len(entries))
+	for _, e := range entries {
+		if e.Opponent != "" && e.Opponent != BYE.Name() {
+			*w = append(*w, e)
+		}
+	}
+	return nil
+}
+
+// ParseWeekNotation parses a week in the compact notation, with games joined by '+'.
+func ParseWeekNotation(notation string) ScheduleWeek {
+	w := make(ScheduleWeek, 0, 1)
+	for _, opponent := range strings.Split(notation, gameSeparator) {
+		opponent = strings.TrimSpace(opponent)
+		if opponent != "" && opponent != BYE.Name() {
+			w = append(w, ScheduleEntry{Opponent: opponent})
+		}
+	}
+	return w
+}
+
+// Notation returns the week in the compact notation, without kickoff times or venues.
+func (w ScheduleWeek) Notation() string {
+	opponents := make([]string, len(w))
+	for i, e := range w {
+		opponents[i] = e.Opponent
+	}
+	return strings.Join(opponents, gameSeparator)
+}
+
+// String returns every game of the week with its kickoff time and venue, if known, or "BYE" for a week without games.
+func (w ScheduleWeek) String() string {
+	if len(w) == 0 {
+		return BYE.Name()
+	}
+	games := make([]string, len(w))
+	for i, e := range w {
+		games[i] = e.String()
+	}
+	return strings.Join(games, " + ")
+}
+
+// Games makes the games of the week for the given team.
+func (w ScheduleWeek) Games(team Team) []*Game {
+	games := make([]*Game, len(w))
+	for i, e := range w {
+		games[i] = e.Game(team)
+	}
+	return games
+}
+
+// ScheduleEntry is one game of a team's schedule in the YAML notation.
+// In YAML, it is either the compact notation of the opponent alone, like "@AAA", or a mapping in long form like
+// {opponent: "@AAA", kickoff: "2020-09-05T19:00:00-05:00", venue: "Soldier Field", status: "postponed", travel: [412.5, 0]}.
+type ScheduleEntry struct {
+	// Opponent is the opponent in the compact notation.
+	Opponent string
+	// Kickoff is the date and time the game starts, or zero if unknown.
+	Kickoff time.Time
+	// Venue is the name of the venue where the game is played, or empty if unknown.
+	Venue string
+	// Status is whether the game is played as scheduled.
+	Status GameStatus
+	// Travel is the distances in miles the team and the opponent travel to the game, or nil if unknown.
+	Travel []float64
+}
+
+// UnmarshalYAML parses either the compact or the long form of an entry.
+func (e *ScheduleEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var opponent string
+	if err := unmarshal(&opponent); err == nil {
+		*e = ScheduleEntry{Opponent: opponent}
+		return nil
+	}
+
+	var long struct {
+		Opponent string    `yaml:"opponent"`
+		Kickoff  string    `yaml:"kickoff"`
+		Venue    string    `yaml:"venue"`
+		Status   string    `yaml:"status"`
+		Travel   []float64 `yaml:"travel"`
+	}
+	if err := unmarshal(&long); err != nil {
+		return err
+	}
+	if long.Travel != nil && len(long.Travel) != 2 {
+		return fmt.Errorf("travel of game against %s: expected distances for both teams, got %v", long.Opponent, long.Travel)
+	}
+	status, err := ParseGameStatus(long.Status)
+	if err != nil {
+		return err
+	}
+	*e = ScheduleEntry{Opponent: long.Opponent, Venue: long.Venue, Status: status, Travel: long.Travel}
+	if long.Kickoff != "" {
+		kickoff, err := ParseKickoff(long.Kickoff)
+		if err != nil {
+			return err
+		}
+		e.Kickoff = kickoff
+	}
+	return nil
+}
+
+// kickoffFormats are the formats of kickoff times understood by ParseKickoff, from most to least specific.
+var kickoffFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// ParseKickoff parses a kickoff time in RFC 3339 format, or as a date and time or a date alone, which are taken to be UTC.
+func ParseKickoff(s string) (time.Time, error) {
+	for _, format := range kickoffFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse kickoff time \"%s\"", s)
+}
+
+// String returns the compact notation of the opponent followed by the kickoff time, venue, and status, if known and not scheduled.
+func (e ScheduleEntry) String() string {
+	extra := make([]string, 0, 3)
+	if !e.Kickoff.IsZero() {
+		extra = append(extra, e.Kickoff.Format(time.RFC3339))
+	}
+	if e.Venue != "" {
+		extra = append(extra, e.Venue)
+	}
+	if e.Status != Scheduled {
+		extra = append(extra, e.Status.String())
+	}
+	if len(extra) == 0 {
+		return e.Opponent
+	}
+	return fmt.Sprintf("%s (%s)", e.Opponent, strings.Join(extra, ", "))
+}
+
+// Game makes the game the entry describes for the given team.
+func (e ScheduleEntry) Game(team Team) *Game {
+	loc, team2 := splitLocTeam(e.Opponent)
+	g := NewGame(team, team2, loc)
+	g.SetKickoff(e.Kickoff)
+	g.SetVenue(e.Venue)
+	g.SetStatus(e.Status)
+	if len(e.Travel) == 2 {
+		g.SetTravel(e.Travel[0], e.Travel[1])
+	}
+	return g
+}
+
+// Entry returns the schedule entry describing the game for the first team.
+func (g *Game) Entry() ScheduleEntry {
+	e := ScheduleEntry{Opponent: g.Notation(), Kickoff: g.kickoff, Venue: g.venue, Status: g.status}
+	if g.hasTravel {
+		e.Travel = []float64{g.travel[0], g.travel[1]}
+	}
+	return e
+}
+
+// Week returns the games a team plays in a week as a schedule week.
+func (s Schedule) Week(t Team, w int) ScheduleWeek {
+	games := s.Games(t, w)
+	week := make(ScheduleWeek, len(games))
+	for i, g := range games {
+		week[i] = g.Entry()
+	}
+	return week
+}
+
+// ScheduleFromWeeks makes a schedule from a map of team names to schedule weeks.
+func ScheduleFromWeeks(s map[string][]ScheduleWeek) Schedule {
 	sched := make(Schedule)
-	for team, locteams := range s {
-		sched[team] = make([]*Game, len(locteams))
-		for i, locteam := range locteams {
-			loc, team2 := splitLocTeam(locteam)
-			sched[team][i] = NewGame(team, team2, loc)
+	for name, weeks := range s {
+		team := Team{Name4: name}
+		sched[team] = make([][]*Game, len(weeks))
+		for i, w := range weeks {
+			sched[team][i] = w.Games(team)
 		}
 	}
+	return sched
+}
 
-	return &sched, nil
+// ScheduleFromNotation makes a schedule from a map of team names to weeks in the compact YAML notation.
+func ScheduleFromNotation(s map[string][]string) Schedule {
+	sched := make(Schedule)
+	for name, notations := range s {
+		team := Team{Name4: name}
+		sched[team] = make([][]*Game, len(notations))
+		for i, notation := range notations {
+			sched[team][i] = ParseWeekNotation(notation).Games(team)
+		}
+	}
+	return sched
 }
 
-// Get a game for a team and week number.
-func (s Schedule) Get(t Team, w int) *Game {
+// Games gets the games a team plays in a week. Teams play no games in bye weeks.
+// Picking no team (NONE) plays only NULLGAME.
+func (s Schedule) Games(t Team, w int) []*Game {
 	if t == NONE {
 		// Picking no team is strange
-		return &NULLGAME
+		return []*Game{&NULLGAME}
 	}
 	return s[t][w]
 }
 
+// Get gets the first game a team plays in a week, or a game against BYE if the team plays no games that week.
+// Use Games for weeks in which a team might play more than once.
+func (s Schedule) Get(t Team, w int) *Game {
+	games := s.Games(t, w)
+	if len(games) == 0 {
+		return NewGame(t, BYE, Neutral)
+	}
+	return games[0]
+}
+
+// PlaysIn reports whether a team plays any games in a week.
+func (s Schedule) PlaysIn(t Team, w int) bool {
+	return len(s.Games(t, w)) > 0
+}
+
 // NumWeeks returns the number of weeks contained in the schedule.
 func (s Schedule) NumWeeks() int {
 	for _, v := range s {
@@ -59,6 +295,29 @@ func (s Schedule) NumWeeks() int {
 	return 0
 }
 
+// WeekOf determines the week in progress at a given time: the first week with a game kicking off at or after that time.
+// If every game has already kicked off, it returns the number of weeks in the schedule.
+// The second return value reports whether any game in the schedule has a known kickoff time.
+func (s Schedule) WeekOf(t time.Time) (int, bool) {
+	known := false
+	week := s.NumWeeks()
+	for _, weeks := range s {
+		for w, games := range weeks {
+			for _, g := range games {
+				kickoff, ok := g.Kickoff()
+				if !ok {
+					continue
+				}
+				known = true
+				if w < week && !kickoff.Before(t) {
+					week = w
+				}
+			}
+		}
+	}
+	return week, known
+}
+
 // FilterWeeks filters the Predictions by removing weeks prior to the given one.
 func (s *Schedule) FilterWeeks(w int) {
 	if w <= 0 {
@@ -101,6 +360,7 @@ func splitLocTeam(locTeam string) (RelativeLocation, Team) {
 
 func (s Schedule) String() string {
 	tl := s.TeamList()
+	sort.Sort(tl)
 	nW := s.NumWeeks()
 	var b strings.Builder
 
@@ -113,24 +373,18 @@ func (s Schedule) String() string {
 	for _, team := range tl {
 		b.WriteString(fmt.Sprintf("%4s: ", team.Name()))
 		for week := 0; week < nW; week++ {
-			g := s.Get(team, week)
-			extra := ' '
-			switch g.LocationRelativeToTeam(0) {
-			case Away:
-				extra = '@'
-			case Far:
-				extra = '>'
-			case Near:
-				extra = '<'
-			case Neutral:
-				extra = '!'
+			cells := make([]string, 0, 1)
+			for _, g := range s.Games(team, week) {
+				extra := LocationPrefix(g.LocationRelativeToTeam(0))
+				if extra == "" {
+					extra = " "
+				}
+				cells = append(cells, extra+g.Team(1).Name())
 			}
-			if g.Team(1) != BYE {
-				b.WriteRune(extra)
-			} else {
-				b.WriteRune(' ')
+			if len(cells) == 0 {
+				cells = append(cells, " "+BYE.Name())
 			}
-			b.WriteString(fmt.Sprintf("%-4s ", g.Team(1).Name()))
+			b.WriteString(fmt.Sprintf("%-5s ", strings.Join(cells, gameSeparator)))
 		}
 		b.WriteString("\n")
 	}
@@ -0,0 +1,252 @@
+package bts
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocationPrefix is the mark placed before an opponent in the compact YAML notation to show where a game is played relative to the scheduled team.
+func LocationPrefix(loc RelativeLocation) string {
+	switch loc {
+	case Away:
+		return "@"
+	case Far:
+		return ">"
+	case Near:
+		return "<"
+	case Neutral:
+		return "!"
+	default:
+		return ""
+	}
+}
+
+// Notation returns the opponent of the first team in the compact YAML notation, or an empty string for a bye.
+func (g *Game) Notation() string {
+	if g.Team(1) == BYE {
+		return ""
+	}
+	return LocationPrefix(g.LocationRelativeToTeam(0)) + g.Team(1).Name()
+}
+
+// describe returns the opponent of the first team in words, like "vs AAA" for a home game or "at AAA" for an away game,
+// followed by the status of the game in brackets if it is not played as scheduled.
+func (g *Game) describe() string {
+	if g.Team(1) == BYE {
+		return "BYE"
+	}
+	if g.status != Scheduled {
+		return g.describeLocation() + " [" + g.status.String() + "]"
+	}
+	return g.describeLocation()
+}
+
+func (g *Game) describeLocation() string {
+	opp := g.Team(1).Name()
+	switch g.LocationRelativeToTeam(0) {
+	case Home:
+		return "vs " + opp
+	case Near:
+		return "vs " + opp + " (near)"
+	case Neutral:
+		return "vs " + opp + " (neutral)"
+	case Far:
+		return "at " + opp + " (far)"
+	default:
+		return "at " + opp
+	}
+}
+
+// describeWeek describes the games a team plays in a week in words, joined by " + ", or "BYE" for a week without games.
+func (s Schedule) describeWeek(team Team, week int) string {
+	games := s.Games(team, week)
+	if len(games) == 0 {
+		return BYE.Name()
+	}
+	described := make([]string, len(games))
+	for i, g := range games {
+		described[i] = g.describe()
+	}
+	return strings.Join(described, " + ")
+}
+
+// sortedTeams lists the teams of the schedule in alphabetical order.
+func (s Schedule) sortedTeams() TeamList {
+	tl := s.TeamList()
+	sort.Sort(tl)
+	return tl
+}
+
+// plainYaml matches strings that can be written in YAML without quotes.
+var plainYaml = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]*$`)
+
+func yamlString(s string) string {
+	if plainYaml.MatchString(s) && !strings.HasSuffix(s, " ") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// yamlWeek formats a week in the compact notation if none of its games has a kickoff time, venue, status, or travel distances,
+// otherwise a single game in long form or a list of games.
+func yamlWeek(w ScheduleWeek) string {
+	compact := true
+	for _, e := range w {
+		compact = compact && e.Kickoff.IsZero() && e.Venue == "" && e.Status == Scheduled && e.Travel == nil
+	}
+	if compact {
+		return strconv.Quote(w.Notation())
+	}
+	if len(w) == 1 {
+		return yamlEntry(w[0])
+	}
+	entries := make([]string, len(w))
+	for i, e := range w {
+		entries[i] = yamlEntry(e)
+	}
+	return "[" + strings.Join(entries, ", ") + "]"
+}
+
+// yamlEntry formats a game in the compact notation if possible, otherwise in long form.
+func yamlEntry(e ScheduleEntry) string {
+	if e.Kickoff.IsZero() && e.Venue == "" && e.Status == Scheduled && e.Travel == nil {
+		return strconv.Quote(e.Opponent)
+	}
+	fields := []string{"opponent: " + strconv.Quote(e.Opponent)}
+	if !e.Kickoff.IsZero() {
+		fields = append(fields, "kickoff: "+strconv.Quote(e.Kickoff.Format(time.RFC3339)))
+	}
+	if e.Venue != "" {
+		fields = append(fields, "venue: "+strconv.Quote(e.Venue))
+	}
+	if e.Status != Scheduled {
+		fields = append(fields, "status: "+strconv.Quote(e.Status.String()))
+	}
+	if e.Travel != nil {
+		fields = append(fields, fmt.Sprintf("travel: [%s, %s]", strconv.FormatFloat(e.Travel[0], 'f', -1, 64), strconv.FormatFloat(e.Travel[1], 'f', -1, 64)))
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// WriteYaml writes the schedule in the compact YAML notation read by ParseSchedule, one team per line in alphabetical order.
+// Weeks with known kickoff times, venues, statuses, or travel distances are written in long form. Weeks are aligned in columns.
+// Weeks with more than one game join the opponents with '+', or list the games in long form.
+func (s Schedule) WriteYaml(w io.Writer) error {
+	tl := s.sortedTeams()
+	nW := s.NumWeeks()
+
+	keys := make([]string, len(tl))
+	keyWidth := 0
+	cells := make([][]string, len(tl))
+	widths := make([]int, nW)
+	for i, team := range tl {
+		keys[i] = yamlString(team.Name()) + ":"
+		if len(keys[i]) > keyWidth {
+			keyWidth = len(keys[i])
+		}
+		cells[i] = make([]string, nW)
+		for week := 0; week < nW; week++ {
+			cells[i][week] = yamlWeek(s.Week(team, week))
+			if len(cells[i][week]) > widths[week] {
+				widths[week] = len(cells[i][week])
+			}
+		}
+	}
+
+	for i := range tl {
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("%-*s [", keyWidth, keys[i]))
+		for week, cell := range cells[i] {
+			if week < nW-1 {
+				b.WriteString(fmt.Sprintf("%-*s ", widths[week]+1, cell+","))
+			} else {
+				b.WriteString(fmt.Sprintf("%-*s", widths[week], cell))
+			}
+		}
+		b.WriteString("]\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes the schedule as CSV with a header row of week numbers and one row per team in alphabetical order.
+// Opponents are written in the compact YAML notation, with empty cells for byes and '+' between the games of weeks with more than one game.
+func (s Schedule) WriteCSV(w io.Writer) error {
+	nW := s.NumWeeks()
+	cw := csv.NewWriter(w)
+	header := make([]string, nW+1)
+	header[0] = "team"
+	for week := 0; week < nW; week++ {
+		header[week+1] = strconv.Itoa(week)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, team := range s.sortedTeams() {
+		row := make([]string, nW+1)
+		row[0] = team.Name()
+		for week := 0; week < nW; week++ {
+			row[week+1] = s.Week(team, week).Notation()
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarkdown writes the schedule as a Markdown table with one row per team in alphabetical order and one column per week.
+func (s Schedule) WriteMarkdown(w io.Writer) error {
+	nW := s.NumWeeks()
+	escape := strings.NewReplacer("|", `\|`)
+
+	var b strings.Builder
+	b.WriteString("| Team |")
+	for week := 0; week < nW; week++ {
+		b.WriteString(fmt.Sprintf(" %d |", week))
+	}
+	b.WriteString("\n|---|")
+	b.WriteString(strings.Repeat("---|", nW))
+	b.WriteString("\n")
+	for _, team := range s.sortedTeams() {
+		b.WriteString(fmt.Sprintf("| %s |", escape.Replace(team.Name())))
+		for week := 0; week < nW; week++ {
+			b.WriteString(fmt.Sprintf(" %s |", escape.Replace(s.describeWeek(team, week))))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteHTML writes the schedule as an HTML table with one row per team in alphabetical order and one column per week.
+func (s Schedule) WriteHTML(w io.Writer) error {
+	nW := s.NumWeeks()
+
+	var b strings.Builder
+	b.WriteString("<table>\n<thead>\n<tr><th>Team</th>")
+	for week := 0; week < nW; week++ {
+		b.WriteString(fmt.Sprintf("<th>%d</th>", week))
+	}
+	b.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, team := range s.sortedTeams() {
+		b.WriteString(fmt.Sprintf("<tr><th>%s</th>", html.EscapeString(team.Name())))
+		for week := 0; week < nW; week++ {
+			b.WriteString(fmt.Sprintf("<td>%s</td>", html.EscapeString(s.describeWeek(team, week))))
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
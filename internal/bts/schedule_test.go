@@ -0,0 +1,215 @@
+package bts
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testScheduleYaml = `AAA: ["!BBB", ">CCC", "<DDD", "",     "@EEE"]
+BBB: ["!AAA", "",     "!EEE", "@DDD", "CCC" ]
+CCC: ["DDD",  "<AAA", "",     "@EEE", "@BBB"]
+DDD: ["@CCC", "@EEE", ">AAA", "BBB",  ""    ]
+EEE: ["",     "DDD",  "!BBB", "CCC",  "AAA" ]
+`
+
+func TestScheduleYamlRoundTrip(t *testing.T) {
+	s, err := ParseSchedule(strings.NewReader(testScheduleYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := s.Get(Team{"AAA"}, 1); g.Team(1) != (Team{"CCC"}) || g.LocationRelativeToTeam(0) != Far {
+		t.Errorf("expected AAA week 1 to be far from CCC, got %v", g)
+	}
+	if g := s.Get(Team{"AAA"}, 3); g.Team(1) != BYE {
+		t.Errorf("expected AAA week 3 to be a bye, got %v", g)
+	}
+
+	var b bytes.Buffer
+	if err := s.WriteYaml(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != testScheduleYaml {
+		t.Errorf("expected\n%s\ngot\n%s", testScheduleYaml, b.String())
+	}
+
+	s2, err := ParseSchedule(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, s2) {
+		t.Errorf("expected round trip to give the same schedule, got\n%v", s2)
+	}
+}
+
+func TestScheduleExport(t *testing.T) {
+	s := ScheduleFromNotation(map[string][]string{
+		"BBB": {"@AAA", ""},
+		"AAA": {"BBB", "!C|C"},
+	})
+
+	var b strings.Builder
+	if err := s.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := "team,0,1\nAAA,BBB,!C|C\nBBB,@AAA,\n"
+	if b.String() != expected {
+		t.Errorf("CSV: expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := s.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected = "| Team | 0 | 1 |\n|---|---|---|\n| AAA | vs BBB | vs C\\|C (neutral) |\n| BBB | at AAA | BYE |\n"
+	if b.String() != expected {
+		t.Errorf("Markdown: expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := s.WriteHTML(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<tr><th>BBB</th><td>at AAA</td><td>BYE</td></tr>") {
+		t.Errorf("HTML: unexpected table\n%s", b.String())
+	}
+}
+
+func TestScheduleLongForm(t *testing.T) {
+	in := `AAA: [{opponent: "@BBB", kickoff: "2020-09-05T19:00:00-05:00", venue: "BBB Field"}, "", {opponent: "CCC", kickoff: "2020-09-19"}]
+BBB: [{opponent: "AAA", kickoff: "2020-09-05T19:00:00-05:00", venue: "BBB Field"}, "", ""]
+CCC: ["", "", {opponent: "@AAA", kickoff: "2020-09-19", travel: [120.5, 0]}]
+`
+	s, err := ParseSchedule(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := s.Get(Team{"AAA"}, 0)
+	kickoff, ok := g.Kickoff()
+	if !ok || !kickoff.Equal(time.Date(2020, time.September, 6, 0, 0, 0, 0, time.UTC)) || g.Venue() != "BBB Field" || g.LocationRelativeToTeam(0) != Away {
+		t.Errorf("unexpected game %v at %s (%t) in %s", g, kickoff, ok, g.Venue())
+	}
+	if _, ok := s.Get(Team{"AAA"}, 1).Kickoff(); ok {
+		t.Errorf("expected no kickoff for bye")
+	}
+	if d, ok := s.Get(Team{"CCC"}, 2).Travel(0); !ok || d != 120.5 {
+		t.Errorf("expected travel 120.5, got %f (%t)", d, ok)
+	}
+	if _, ok := g.Travel(0); ok {
+		t.Errorf("expected no travel for game without distances")
+	}
+
+	var b bytes.Buffer
+	if err := s.WriteYaml(&b); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := ParseSchedule(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for team, weeks := range *s {
+		for week := range weeks {
+			if w1, w2 := s.Week(team, week), s2.Week(team, week); !reflect.DeepEqual(w1, w2) {
+				t.Errorf("%s week %d: expected %v, got %v after round trip", team.Name(), week, w1, w2)
+			}
+		}
+	}
+
+	if _, err := ParseSchedule(strings.NewReader(`AAA: [{opponent: "BBB", kickoff: "next Tuesday"}]`)); err == nil {
+		t.Errorf("expected error for bad kickoff")
+	}
+	if _, err := ParseSchedule(strings.NewReader(`AAA: [{opponent: "BBB", travel: [12]}]`)); err == nil {
+		t.Errorf("expected error for travel without both distances")
+	}
+}
+
+func TestWeekOf(t *testing.T) {
+	s := ScheduleFromNotation(map[string][]string{"AAA": {"BBB", "", "CCC"}, "BBB": {"@AAA", "", ""}})
+	if _, ok := s.WeekOf(time.Now()); ok {
+		t.Errorf("expected unknown week without kickoffs")
+	}
+
+	sat := time.Date(2020, time.September, 5, 18, 0, 0, 0, time.UTC)
+	s.Get(Team{"AAA"}, 0).SetKickoff(sat)
+	s.Get(Team{"BBB"}, 0).SetKickoff(sat)
+	s.Get(Team{"AAA"}, 2).SetKickoff(sat.AddDate(0, 0, 14))
+
+	tests := []struct {
+		t        time.Time
+		expected int
+	}{
+		{sat.AddDate(0, 0, -3), 0},
+		{sat, 0},
+		// After week 0 kicks off, the next game is in week 2.
+		{sat.Add(time.Hour), 2},
+		{sat.AddDate(0, 0, 15), 3},
+	}
+	for _, test := range tests {
+		if w, ok := s.WeekOf(test.t); !ok || w != test.expected {
+			t.Errorf("at %s: expected week %d, got %d (%t)", test.t, test.expected, w, ok)
+		}
+	}
+}
+
+func TestMultipleGamesPerWeek(t *testing.T) {
+	in := `AAA: ["BBB+@CCC", "BYE", [{opponent: "!DDD", venue: "Soldier Field"}, "@BBB"]]
+BBB: ["@AAA", "", "AAA"]
+CCC: ["AAA", "DDD", ""]
+DDD: ["", "@CCC", {opponent: "!AAA", venue: "Soldier Field"}]
+`
+	s, err := ParseSchedule(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aaa := Team{"AAA"}
+	if n := len(s.Games(aaa, 0)); n != 2 {
+		t.Errorf("expected AAA to play 2 games in week 0, got %d", n)
+	}
+	if s.PlaysIn(aaa, 1) || s.Get(aaa, 1).Team(1) != BYE {
+		t.Errorf("expected AAA to have a bye in week 1")
+	}
+	if w := s.Week(aaa, 2).String(); w != "!DDD (Soldier Field) + @BBB" {
+		t.Errorf("expected week 2 of AAA to be \"!DDD (Soldier Field) + @BBB\", got \"%s\"", w)
+	}
+
+	var b bytes.Buffer
+	if err := s.WriteYaml(&b); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := ParseSchedule(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for team, weeks := range *s {
+		for week := range weeks {
+			if w1, w2 := s.Week(team, week), s2.Week(team, week); !reflect.DeepEqual(w1, w2) {
+				t.Errorf("%s week %d: expected %v, got %v after round trip", team.Name(), week, w1, w2)
+			}
+		}
+	}
+
+	var c strings.Builder
+	if err := s.WriteCSV(&c); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.String(), "AAA,BBB+@CCC,,!DDD+@BBB\n") {
+		t.Errorf("CSV: unexpected output\n%s", c.String())
+	}
+
+	ratings := map[Team]float64{aaa: 10, {"BBB"}: 0, {"CCC"}: 0, {"DDD"}: 0}
+	model := NewGaussianSpreadModel(ratings, 10, 0, 0)
+	p := MakePredictions(s, model)
+	prob1, spread1 := model.Predict(s.Games(aaa, 0)[0])
+	prob2, spread2 := model.Predict(s.Games(aaa, 0)[1])
+	if prob := p.GetProbability(aaa, 0); prob != prob1*prob2 {
+		t.Errorf("expected probability %f of winning both games, got %f", prob1*prob2, prob)
+	}
+	if spread := p.GetSpread(aaa, 0); spread != spread1+spread2 {
+		t.Errorf("expected total spread %f, got %f", spread1+spread2, spread)
+	}
+	if prob := p.GetProbability(aaa, 1); prob != 0 {
+		t.Errorf("expected probability 0 for bye, got %f", prob)
+	}
+}
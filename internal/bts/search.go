@@ -0,0 +1,352 @@
+package bts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// StreakResult is a streak with its score under an objective, its probability of success, and its total spread.
+type StreakResult struct {
+	Streak      *Streak
+	Score       Score
+	Probability float64
+	Spread      float64
+}
+
+// Better reports whether the result ranks above another by score.
+func (r StreakResult) Better(o StreakResult) bool {
+	return r.Score.Better(o.Score)
+}
+
+// TopStreaks keeps the best K distinct streaks pushed to it, ordered best first.
+// Streaks are distinct if their canonical forms differ. Hashes are only used to find candidate duplicates, which are then compared in full.
+type TopStreaks struct {
+	k       int
+	results []StreakResult
+	hashes  []uint64
+}
+
+// NewTopStreaks makes an empty TopStreaks that keeps at most k results.
+func NewTopStreaks(k int) *TopStreaks {
+	if k < 1 {
+		k = 1
+	}
+	return &TopStreaks{k: k, results: make([]StreakResult, 0, k), hashes: make([]uint64, 0, k)}
+}
+
+// Accepts reports whether a result with the given score would be kept if pushed.
+// This is useful for avoiding cloning streaks that would be immediately discarded.
+func (t *TopStreaks) Accepts(score Score) bool {
+	if len(t.results) < t.k {
+		return true
+	}
+	return score.Better(t.results[len(t.results)-1].Score)
+}
+
+// Push adds a result if it ranks among the best K distinct streaks seen so far, reporting whether it was kept.
+// The streak is stored as given, so callers should pass a streak they will not modify later.
+func (t *TopStreaks) Push(r StreakResult) bool {
+	if !t.Accepts(r.Score) {
+		return false
+	}
+	return t.insert(r, r.Streak.Hash())
+}
+
+// PushClone adds a clone of the streak if it ranks among the best K distinct streaks seen so far, reporting whether it was kept.
+// The streak is only cloned if it is kept, so this is the cheapest way to push a streak that is still being modified.
+func (t *TopStreaks) PushClone(s *Streak, score Score, prob, spread float64) bool {
+	if !t.Accepts(score) {
+		return false
+	}
+	h := s.Hash()
+	if t.contains(s, h) {
+		return false
+	}
+	return t.insert(StreakResult{Streak: s.Clone(), Score: score, Probability: prob, Spread: spread}, h)
+}
+
+// contains reports whether an equivalent streak is kept. The streak's hash is h.
+func (t *TopStreaks) contains(s *Streak, h uint64) bool {
+	for i, x := range t.hashes {
+		if x == h && t.results[i].Streak.equivalent(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TopStreaks) insert(r StreakResult, h uint64) bool {
+	if t.contains(r.Streak, h) {
+		return false
+	}
+	i := sort.Search(len(t.results), func(i int) bool { return r.Better(t.results[i]) })
+	if len(t.results) < t.k {
+		t.results = append(t.results, StreakResult{})
+		t.hashes = append(t.hashes, 0)
+	}
+	copy(t.results[i+1:], t.results[i:])
+	copy(t.hashes[i+1:], t.hashes[i:])
+	t.results[i] = r
+	t.hashes[i] = h
+	return true
+}
+
+// Merge pushes all of the results from another TopStreaks.
+func (t *TopStreaks) Merge(o *TopStreaks) {
+	for i, r := range o.results {
+		if t.Accepts(r.Score) {
+			t.insert(r, o.hashes[i])
+		}
+	}
+}
+
+// Len returns the number of results kept.
+func (t *TopStreaks) Len() int {
+	return len(t.results)
+}
+
+// Results returns the kept results, best first.
+func (t *TopStreaks) Results() []StreakResult {
+	out := make([]StreakResult, len(t.results))
+	copy(out, t.results)
+	return out
+}
+
+// RankRange is a half-open range [Start, End) of streak ranks.
+type RankRange struct {
+	Start *big.Int
+	End   *big.Int
+}
+
+// Len returns the number of ranks in the range.
+func (r RankRange) Len() *big.Int {
+	return new(big.Int).Sub(r.End, r.Start)
+}
+
+func (r RankRange) String() string {
+	return fmt.Sprintf("[%v,%v)", r.Start, r.End)
+}
+
+// ShardRanks splits the ranks [0, total) into n disjoint, contiguous ranges of nearly equal size.
+func ShardRanks(total *big.Int, n int) []RankRange {
+	if n < 1 {
+		n = 1
+	}
+	out := make([]RankRange, n)
+	bn := big.NewInt(int64(n))
+	for i := 0; i < n; i++ {
+		start := new(big.Int).Mul(total, big.NewInt(int64(i)))
+		start.Div(start, bn)
+		end := new(big.Int).Mul(total, big.NewInt(int64(i+1)))
+		end.Div(end, bn)
+		out[i] = RankRange{Start: start, End: end}
+	}
+	return out
+}
+
+// NumberOfStreaks returns the number of distinct streaks the player could pick: every order of the remaining teams combined with every distinct order of the remaining week types.
+func (p Player) NumberOfStreaks() *big.Int {
+	n := factorial(len(p.remaining))
+	return n.Mul(n, p.weekTypes.NumberOfPermutations())
+}
+
+// StreakAt returns the streak of the given rank.
+// Streaks are ranked with week type orders varying slowest and team orders varying fastest, each in lexicographic order.
+func (p Player) StreakAt(rank *big.Int) (*Streak, error) {
+	if rank.Sign() < 0 || rank.Cmp(p.NumberOfStreaks()) >= 0 {
+		return nil, fmt.Errorf("streak rank %v out of range [0,%v)", rank, p.NumberOfStreaks())
+	}
+	wtRank, teamRank := new(big.Int).DivMod(rank, factorial(len(p.remaining)), new(big.Int))
+	wt, err := p.weekTypes.Permutation(wtRank)
+	if err != nil {
+		return nil, err
+	}
+	order, err := NewIndexPermutor(len(p.remaining)).Permutation(teamRank)
+	if err != nil {
+		return nil, err
+	}
+	s := NewStreak(p.remaining, wt)
+	s.PermuteTeamOrder(order)
+	return s, nil
+}
+
+// nextPermutation rearranges the values into the next permutation in lexicographic order, correctly skipping repeated values.
+// If the values are already in the last permutation, they are rearranged into the first and false is returned.
+func nextPermutation(a []int) bool {
+	i := len(a) - 2
+	for i >= 0 && a[i] >= a[i+1] {
+		i--
+	}
+	if i >= 0 {
+		j := len(a) - 1
+		for a[j] <= a[i] {
+			j--
+		}
+		a[i], a[j] = a[j], a[i]
+	}
+	for l, r := i+1, len(a)-1; l < r; l, r = l+1, r-1 {
+		a[l], a[r] = a[r], a[l]
+	}
+	return i >= 0
+}
+
+// SearchRange evaluates every streak with a rank in the given range and returns the best k of them under the objective.
+// Streaks that violate the player's pick constraints are never generated: whenever a team is placed in a week it is not allowed to be picked,
+// every team order sharing that prefix is skipped.
+// Streaks that are impossible to complete (with a probability of zero) are never kept.
+// The search stops early with the context's error if the context is cancelled.
+func SearchRange(ctx context.Context, p *Player, predictions *Predictions, obj Objective, r RankRange, k int) (*TopStreaks, error) {
+	top := NewTopStreaks(k)
+	n := r.Len()
+	if n.Sign() <= 0 {
+		return top, nil
+	}
+	if !n.IsInt64() {
+		return nil, fmt.Errorf("rank range %v too large to search", r)
+	}
+
+	nTeams := len(p.remaining)
+	wtRank, teamRank := new(big.Int).DivMod(r.Start, factorial(nTeams), new(big.Int))
+	wt, err := p.weekTypes.Permutation(wtRank)
+	if err != nil {
+		return nil, err
+	}
+	order, err := NewIndexPermutor(nTeams).Permutation(teamRank)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewStreak(p.remaining, wt)
+	avail := p.availability
+	slotWeeks := make([]int, nTeams)
+	setSlotWeeks(slotWeeks, wt)
+
+	var weekProbs []float64
+	iterations := 0
+	for i := int64(0); i < n.Int64(); {
+		if iterations%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		iterations++
+
+		step := int64(1)
+		bad := firstUnavailable(avail, p.remaining, order, slotWeeks)
+		if bad >= 0 {
+			// Skip to the last order with this prefix: the rest of the block is infeasible, too.
+			remaining := n.Int64() - i
+			skip := suffixRemaining(order[bad+1:])
+			if !skip.IsInt64() || skip.Int64() >= remaining {
+				break
+			}
+			step = skip.Int64()
+			sortDescending(order[bad+1:])
+		} else {
+			for j, idx := range order {
+				s.teamOrder[j] = p.remaining[idx]
+			}
+			var spread float64
+			weekProbs, spread = WeekProbabilities(predictions, s, weekProbs)
+			if prob := product(weekProbs); prob > 0 {
+				top.PushClone(s, obj.Score(weekProbs, spread), prob, spread)
+			}
+		}
+
+		if !nextPermutation(order) {
+			nextPermutation(wt)
+			copy(s.numberOfPicks, wt)
+			setSlotWeeks(slotWeeks, wt)
+		}
+		i += step
+	}
+
+	return top, nil
+}
+
+// setSlotWeeks fills slotWeeks with the week of each pick given the picks per week.
+func setSlotWeeks(slotWeeks []int, picksPerWeek []int) {
+	slot := 0
+	for week, ppw := range picksPerWeek {
+		for i := 0; i < ppw; i++ {
+			slotWeeks[slot] = week
+			slot++
+		}
+	}
+}
+
+// firstUnavailable returns the first position in the order at which a team is placed in a week it may not be picked, or -1 if the order is feasible.
+func firstUnavailable(avail Availability, remaining Remaining, order []int, slotWeeks []int) int {
+	if avail == nil {
+		return -1
+	}
+	for j, idx := range order {
+		if !avail.Allows(remaining[idx], slotWeeks[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// suffixRemaining returns the number of permutations of the suffix from the current one to the last one in lexicographic order, inclusive.
+func suffixRemaining(suffix []int) *big.Int {
+	rank := new(big.Int)
+	term := new(big.Int)
+	for i, x := range suffix {
+		smaller := 0
+		for _, y := range suffix[i+1:] {
+			if y < x {
+				smaller++
+			}
+		}
+		term.Mul(big.NewInt(int64(smaller)), factorial(len(suffix)-1-i))
+		rank.Add(rank, term)
+	}
+	return rank.Sub(factorial(len(suffix)), rank)
+}
+
+// sortDescending sorts the values in descending order, which is the last permutation in lexicographic order.
+func sortDescending(a []int) {
+	sort.Sort(sort.Reverse(sort.IntSlice(a)))
+}
+
+// SearchShards evaluates every streak the player could pick by splitting the ranks into the given number of shards, searching each shard concurrently, and merging the best k results of each shard under the objective.
+func SearchShards(ctx context.Context, p *Player, predictions *Predictions, obj Objective, shards int, k int) (*TopStreaks, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ranges := ShardRanks(p.NumberOfStreaks(), shards)
+	tops := make([]*TopStreaks, len(ranges))
+	errs := make([]error, len(ranges))
+
+	var wg sync.WaitGroup
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(i int, r RankRange) {
+			defer wg.Done()
+			tops[i], errs[i] = SearchRange(ctx, p, predictions, obj, r, k)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	// Report the error that caused the cancellation rather than the cancellation itself.
+	for _, err := range errs {
+		if err != nil && err != context.Canceled {
+			return nil, err
+		}
+	}
+	out := NewTopStreaks(k)
+	for i, top := range tops {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		out.Merge(top)
+	}
+	return out, nil
+}
@@ -0,0 +1,163 @@
+package bts
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func randomPredictions(tl TeamList, nWeeks int, seed int64) *Predictions {
+	rng := rand.New(rand.NewSource(seed))
+	p := EmptyPredictions(tl, nWeeks)
+	for _, team := range tl {
+		for week := 0; week < nWeeks; week++ {
+			p.probs[team][week] = rng.Float64()
+			p.spreads[team][week] = rng.NormFloat64() * 10
+		}
+	}
+	return p
+}
+
+func TestTopStreaks(t *testing.T) {
+	top := NewTopStreaks(3)
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}}
+	for i, prob := range []float64{.1, .5, .3, .5, .2, .9} {
+		s := NewStreak(rem, []int{1, 1, 1})
+		s.PermuteTeamOrder(NewIndexPermutor(3).Random(rand.New(rand.NewSource(int64(i)))))
+		s.teamOrder[0] = Team{string(rune('D' + i))}
+		top.Push(StreakResult{Streak: s, Score: Score{Value: prob, Tiebreak: float64(i)}})
+	}
+
+	results := top.Results()
+	expected := []Score{{Value: .9, Tiebreak: 5}, {Value: .5, Tiebreak: 3}, {Value: .5, Tiebreak: 1}}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i := range expected {
+		if results[i].Score != expected[i] {
+			t.Errorf("result %d: expected %v, got %v", i, expected[i], results[i])
+		}
+	}
+
+	if top.Accepts(Score{Value: .2, Tiebreak: 100}) {
+		t.Errorf("expected %v to be rejected", .2)
+	}
+
+	// Duplicates are not kept, even if they differ in the order of picks within a week.
+	best := results[0].Streak
+	if top.PushClone(best, Score{Value: 1}, 1, 0) {
+		t.Errorf("expected duplicate %s to be rejected", best)
+	}
+	dd := NewStreak(Remaining{Team{"A"}, Team{"B"}, Team{"C"}}, []int{2, 1})
+	if !top.PushClone(dd, Score{Value: 1}, 1, 0) {
+		t.Errorf("expected %s to be kept", dd)
+	}
+	dd.PermuteTeamOrder([]int{1, 0, 2})
+	if top.PushClone(dd, Score{Value: 1}, 1, 0) {
+		t.Errorf("expected reordered duplicate %s to be rejected", dd)
+	}
+
+	// Different streaks with colliding hashes are both kept.
+	top = NewTopStreaks(3)
+	s1 := NewStreak(rem, []int{1, 1, 1})
+	s2 := NewStreak(rem, []int{3, 0, 0})
+	if !top.insert(StreakResult{Streak: s1, Score: Score{Value: .5}}, 42) || !top.insert(StreakResult{Streak: s2, Score: Score{Value: .4}}, 42) {
+		t.Errorf("expected %s and %s to be kept despite identical hashes", s1, s2)
+	}
+	if top.insert(StreakResult{Streak: s1.Clone(), Score: Score{Value: .3}}, 42) {
+		t.Errorf("expected duplicate %s to be rejected", s1)
+	}
+}
+
+func TestStreakAt(t *testing.T) {
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}}
+	p, err := NewPlayer("P", rem, []int{1, 1, 1}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pred := randomPredictions(TeamList(rem), 3, 0)
+
+	n := p.NumberOfStreaks()
+	if n.Int64() != 36 {
+		t.Fatalf("expected 36 streaks, got %v", n)
+	}
+
+	seen := make(map[string]bool)
+	for i := int64(0); i < n.Int64(); i++ {
+		s, err := p.StreakAt(big.NewInt(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[s.String()] = true
+
+		// A single-rank search must find exactly the same streak.
+		top, err := SearchRange(context.Background(), p, pred, MaxSurvival{}, RankRange{Start: big.NewInt(i), End: big.NewInt(i + 1)}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prob, _ := SummarizeStreak(pred, s); prob == 0 {
+			continue
+		}
+		if top.Len() != 1 || top.Results()[0].Streak.String() != s.String() {
+			t.Errorf("rank %d: expected %s, got %v", i, s, top.Results())
+		}
+	}
+	if len(seen) != 36 {
+		t.Errorf("expected 36 distinct streaks, got %d", len(seen))
+	}
+}
+
+func TestSearchShards(t *testing.T) {
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}, Team{"D"}}
+	p, err := NewPlayer("P", rem, []int{1, 2, 1}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pred := randomPredictions(TeamList(rem), 4, 1)
+
+	// Brute force for comparison
+	want := NewTopStreaks(5)
+	ctx := context.Background()
+	for wt := range p.WeekTypeIterator(ctx) {
+		for order := range p.RemainingIterator(ctx) {
+			s := NewStreak(rem, wt)
+			s.PermuteTeamOrder(order)
+			prob, spread := SummarizeStreak(pred, s)
+			want.Push(StreakResult{Streak: s, Score: Score{Value: prob, Tiebreak: spread}, Probability: prob, Spread: spread})
+		}
+	}
+
+	for _, shards := range []int{1, 3, 7} {
+		got, err := SearchShards(ctx, p, pred, MaxSurvival{}, shards, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := want.Results()
+		g := got.Results()
+		if len(g) != len(w) {
+			t.Fatalf("%d shards: expected %d results, got %d", shards, len(w), len(g))
+		}
+		for i := range w {
+			if g[i].Streak.Hash() != w[i].Streak.Hash() {
+				t.Errorf("%d shards: result %d expected %v, got %v", shards, i, w[i], g[i])
+			}
+		}
+	}
+}
+
+func TestSearchRangeCancel(t *testing.T) {
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}, Team{"D"}, Team{"E"}, Team{"F"}, Team{"G"}, Team{"H"}}
+	p, err := NewPlayer("P", rem, []int{0, 8}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pred := randomPredictions(TeamList(rem), 8, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := RankRange{Start: big.NewInt(0), End: p.NumberOfStreaks()}
+	if _, err := SearchRange(ctx, p, pred, MaxSurvival{}, r, 1); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
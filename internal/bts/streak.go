@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"sort"
+
+	"github.com/segmentio/fasthash/jody"
 )
 
 // Streak represents a potential streak selection for a contestant.
@@ -77,12 +80,8 @@ func (s *Streak) FindTeam(team Team) int {
 	}
 	n := 0
 	for week, picks := range s.numberOfPicks {
-		if picks == 0 {
-			// count bye as a pick for that week
-			n++
-		} else {
-			n += picks
-		}
+		// weeks without picks hold no teams
+		n += picks
 		if i < n {
 			return week
 		}
@@ -90,6 +89,76 @@ func (s *Streak) FindTeam(team Team) int {
 	return -1
 }
 
+// Canonical returns a clone of the streak with the teams picked in each week sorted by name.
+// Streaks that differ only in the order of teams picked in the same week have identical canonical forms.
+func (s *Streak) Canonical() *Streak {
+	c := s.Clone()
+	pick := 0
+	for _, ppw := range c.numberOfPicks {
+		sort.Sort(c.teamOrder[pick : pick+ppw])
+		pick += ppw
+	}
+	return c
+}
+
+// Hash returns a hash of the canonical form of the streak.
+// Streaks that differ only in the order of teams picked in the same week have identical hashes.
+func (s *Streak) Hash() uint64 {
+	h := jody.HashString64("")
+	pick := 0
+	names := make([]string, 0)
+	for _, ppw := range s.numberOfPicks {
+		names = names[:0]
+		for _, team := range s.teamOrder[pick : pick+ppw] {
+			names = append(names, team.Name())
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			h = jody.AddString64(h, name)
+			// Separate names so that "AB" then "C" and "A" then "BC" hash differently.
+			h = jody.AddString64(h, "\x00")
+		}
+		// Separate weeks so that moving a team to an adjacent week changes the hash.
+		h = jody.AddUint64(h, uint64(ppw))
+		pick += ppw
+	}
+	return h
+}
+
+// equivalent reports whether two streaks have identical canonical forms: the same number of picks in each week, and the same teams picked in each week.
+func (s *Streak) equivalent(o *Streak) bool {
+	if len(s.numberOfPicks) != len(o.numberOfPicks) {
+		return false
+	}
+	for week, ppw := range s.numberOfPicks {
+		if o.numberOfPicks[week] != ppw {
+			return false
+		}
+	}
+	if len(s.teamOrder) != len(o.teamOrder) {
+		return false
+	}
+	pick := 0
+	names := make([]string, 0)
+	others := make([]string, 0)
+	for _, ppw := range s.numberOfPicks {
+		names, others = names[:0], others[:0]
+		for i := pick; i < pick+ppw; i++ {
+			names = append(names, s.teamOrder[i].Name())
+			others = append(others, o.teamOrder[i].Name())
+		}
+		sort.Strings(names)
+		sort.Strings(others)
+		for i := range names {
+			if names[i] != others[i] {
+				return false
+			}
+		}
+		pick += ppw
+	}
+	return true
+}
+
 // NumWeeks returns the number of weeks in the streak.
 func (s *Streak) NumWeeks() int {
 	return len(s.numberOfPicks)
@@ -119,22 +188,93 @@ func (s *Streak) String() string {
 
 // Perturbate randomly swaps two teams in the team order.
 // If `picksPerWeekAlso` is `true`, will also randomly swap the picks per week for two weeks.
+// When the swapped weeks have different numbers of picks, randomly chosen teams move from the week that loses picks to the week that gains them,
+// so every other week keeps its teams and weeks of any number of picks can be perturbed.
 // This function is not guaranteed to produce a new distinct streak.
 func (s *Streak) Perturbate(src rand.Source, picksPerWeekAlso bool) {
+	s.PerturbateAvailable(src, picksPerWeekAlso, nil)
+}
+
+// maxPerturbationAttempts is the number of perturbations proposed by PerturbateAvailable before giving up.
+const maxPerturbationAttempts = 100
+
+// PerturbateAvailable perturbs the streak like Perturbate, but only ever produces streaks in which every team is picked in a week the Availability allows.
+// Perturbations are proposed until one keeps the streak feasible; if none is found after a number of attempts, the streak is left unchanged.
+// The streak is expected to be feasible to begin with.
+func (s *Streak) PerturbateAvailable(src rand.Source, picksPerWeekAlso bool, avail Availability) {
 	if src == nil {
 		src = rand.NewSource(rand.Int63())
 	}
 	rng := rand.New(src)
 
-	a := rng.Intn(s.teamOrder.Len())
-	b := rng.Intn(s.teamOrder.Len())
-	s.teamOrder.Swap(a, b)
+	for attempt := 0; attempt < maxPerturbationAttempts; attempt++ {
+		a := rng.Intn(s.teamOrder.Len())
+		b := rng.Intn(s.teamOrder.Len())
+		wa := s.weekOfPick(a)
+		wb := s.weekOfPick(b)
+		if avail.Allows(s.teamOrder[a], wb) && avail.Allows(s.teamOrder[b], wa) {
+			s.teamOrder.Swap(a, b)
+			break
+		}
+	}
 
 	if picksPerWeekAlso {
-		a = rng.Intn(len(s.numberOfPicks))
-		b = rng.Intn(len(s.numberOfPicks))
-		s.numberOfPicks[a], s.numberOfPicks[b] = s.numberOfPicks[b], s.numberOfPicks[a]
+		for attempt := 0; attempt < maxPerturbationAttempts; attempt++ {
+			a := rng.Intn(len(s.numberOfPicks))
+			b := rng.Intn(len(s.numberOfPicks))
+			if avail == nil {
+				s.swapWeekTypes(rng, a, b)
+				break
+			}
+			proposal := s.Clone()
+			proposal.swapWeekTypes(rng, a, b)
+			if avail.Feasible(proposal) {
+				*s = *proposal
+				break
+			}
+		}
+	}
+}
+
+// weekOfPick returns the week in which the ith team in the team order is picked.
+func (s *Streak) weekOfPick(i int) int {
+	n := 0
+	for week, ppw := range s.numberOfPicks {
+		n += ppw
+		if i < n {
+			return week
+		}
+	}
+	return -1
+}
+
+// swapWeekTypes swaps the number of picks made in weeks a and b, moving randomly selected teams from the week that loses picks to the week that gains them.
+func (s *Streak) swapWeekTypes(rng *rand.Rand, a, b int) {
+	if s.numberOfPicks[a] == s.numberOfPicks[b] {
+		return
+	}
+	if s.numberOfPicks[a] < s.numberOfPicks[b] {
+		a, b = b, a
+	}
+	// Week a has more picks than week b: move the surplus to b.
+	weeks := make([]TeamList, len(s.numberOfPicks))
+	pick := 0
+	for week, ppw := range s.numberOfPicks {
+		weeks[week] = s.teamOrder[pick : pick+ppw].Clone()
+		pick += ppw
+	}
+	rng.Shuffle(len(weeks[a]), weeks[a].Swap)
+	surplus := s.numberOfPicks[a] - s.numberOfPicks[b]
+	keep := len(weeks[a]) - surplus
+	weeks[b] = append(weeks[b], weeks[a][keep:]...)
+	weeks[a] = weeks[a][:keep]
+	s.numberOfPicks[a], s.numberOfPicks[b] = s.numberOfPicks[b], s.numberOfPicks[a]
+
+	order := s.teamOrder[:0]
+	for _, teams := range weeks {
+		order = append(order, teams...)
 	}
+	s.teamOrder = order
 }
 
 // Clone clones the streak. This results in a new struct with all of the internal objects cloned.
@@ -13,6 +13,63 @@ func TestNewStreak(t *testing.T) {
 	t.Log(s)
 }
 
+func TestStreak_GetWeek(t *testing.T) {
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}, Team{"D"}, Team{"E"}, Team{"F"}}
+	s := NewStreak(rem, []int{0, 3, 1, 0, 2})
+
+	expected := []TeamList{{NONE}, {Team{"A"}, Team{"B"}, Team{"C"}}, {Team{"D"}}, {NONE}, {Team{"E"}, Team{"F"}}}
+	for week, want := range expected {
+		got := s.GetWeek(week)
+		if len(got) != len(want) {
+			t.Fatalf("week %d: expected %v, got %v", week, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("week %d: expected %v, got %v", week, want, got)
+			}
+		}
+	}
+
+	expectedWeeks := map[Team]int{Team{"A"}: 1, Team{"C"}: 1, Team{"D"}: 2, Team{"E"}: 4, Team{"F"}: 4, NONE: 0, Team{"Z"}: -1}
+	for team, want := range expectedWeeks {
+		if got := s.FindTeam(team); got != want {
+			t.Errorf("team %s: expected week %d, got %d", team.Name(), want, got)
+		}
+	}
+}
+
+func TestStreak_PerturbateWeekTypes(t *testing.T) {
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}, Team{"D"}, Team{"E"}, Team{"F"}}
+	ppw := []int{0, 3, 1, 0, 2}
+	s := NewStreak(rem, ppw)
+	src := rand.NewSource(0)
+
+	for i := 0; i < 1000; i++ {
+		before := s.Clone()
+		s.Perturbate(src, true)
+
+		// Same teams, same multiset of week types
+		seen := make(map[Team]bool)
+		for week := 0; week < s.NumWeeks(); week++ {
+			for _, team := range s.GetWeek(week) {
+				seen[team] = true
+			}
+		}
+		for _, team := range rem {
+			if !seen[team] {
+				t.Fatalf("team %s lost after perturbing %s into %s", team.Name(), before, s)
+			}
+		}
+		counts := make(map[int]int)
+		for _, n := range s.PicksPerWeek(nil) {
+			counts[n]++
+		}
+		if counts[0] != 2 || counts[1] != 1 || counts[2] != 1 || counts[3] != 1 {
+			t.Fatalf("week types changed after perturbing %s into %s", before, s)
+		}
+	}
+}
+
 func TestStreak_Perturbate(t *testing.T) {
 	type fields struct {
 		numberOfPicks []int
@@ -74,6 +131,42 @@ func TestStreak_Perturbate(t *testing.T) {
 	}
 }
 
+func TestStreak_Hash(t *testing.T) {
+	rem := Remaining{Team{"A"}, Team{"B"}, Team{"C"}, Team{"D"}}
+	s1 := NewStreak(rem, []int{1, 2, 0, 1})
+	s2 := s1.Clone()
+	s2.PermuteTeamOrder([]int{0, 2, 1, 3})
+	s3 := s1.Clone()
+	s3.PermuteTeamOrder([]int{1, 0, 2, 3})
+	s4 := NewStreak(rem, []int{1, 1, 1, 1})
+
+	if s1.Hash() != s2.Hash() {
+		t.Errorf("expected %s and %s to have the same hash", s1, s2)
+	}
+	if s1.Canonical().String() != s2.Canonical().String() {
+		t.Errorf("expected %s and %s to have the same canonical form", s1.Canonical(), s2.Canonical())
+	}
+	if s1.Hash() == s3.Hash() {
+		t.Errorf("expected %s and %s to have different hashes", s1, s3)
+	}
+	if s1.Hash() == s4.Hash() {
+		t.Errorf("expected %s and %s to have different hashes", s1, s4)
+	}
+	if !s1.equivalent(s2) || s1.equivalent(s3) || s1.equivalent(s4) {
+		t.Errorf("expected only %s and %s to be equivalent", s1, s2)
+	}
+
+	// Names are not simply concatenated.
+	s5 := NewStreak(Remaining{Team{"AB"}, Team{"C"}}, []int{2})
+	s6 := NewStreak(Remaining{Team{"A"}, Team{"BC"}}, []int{2})
+	if s5.Hash() == s6.Hash() {
+		t.Errorf("expected %s and %s to have different hashes", s5, s6)
+	}
+	if s5.equivalent(s6) {
+		t.Errorf("expected %s and %s not to be equivalent", s5, s6)
+	}
+}
+
 func BenchmarkStreak_Perturbation(b *testing.B) {
 	src := rand.NewSource(0)
 	numberOfPicks := []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 0}
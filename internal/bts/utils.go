@@ -1,6 +1,7 @@
 package bts
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 )
@@ -21,12 +22,17 @@ func getURLBody(url string) ([]byte, error) {
 // TeamPermute creates all possible permutations of a sort.Interface and issues them
 // to a chan.  This uses the non-recursive form of Heap's algorithm, which is
 // well-suited for a goroutine.  See https://en.wikipedia.org/wiki/Heap%27s_algorithm
-func TeamPermute(s TeamList, c chan<- TeamList) {
+// The chan is closed when all permutations have been issued or when the context is cancelled.
+func TeamPermute(ctx context.Context, s TeamList, c chan<- TeamList) {
 	defer close(c)
 
 	// First permutation: self
 	out := s.Clone()
-	c <- out.Clone()
+	select {
+	case c <- out.Clone():
+	case <-ctx.Done():
+		return
+	}
 
 	count := make([]int, out.Len())
 
@@ -38,7 +44,11 @@ func TeamPermute(s TeamList, c chan<- TeamList) {
 			} else {
 				out.Swap(count[i], i)
 			}
-			c <- out.Clone()
+			select {
+			case c <- out.Clone():
+			case <-ctx.Done():
+				return
+			}
 			count[i]++
 			i = 0
 		} else {
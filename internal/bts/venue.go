@@ -0,0 +1,165 @@
+package bts
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// HomeRadiusMiles is the distance from a team's home stadium within which a game counts as a home game.
+const HomeRadiusMiles = 10.
+
+// NeutralTravelMargin is the largest relative travel difference (see RelativeTravel) at which a game is considered to be at a truly neutral location.
+const NeutralTravelMargin = .2
+
+// earthRadiusMiles is the mean radius of the Earth.
+const earthRadiusMiles = 3958.8
+
+// Venue is a place where games are played.
+type Venue struct {
+	Name      string  `yaml:"name"`
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+}
+
+// Distance calculates the great-circle distance in miles between two venues.
+func (v Venue) Distance(o Venue) float64 {
+	toRad := math.Pi / 180.
+	lat1, lat2 := v.Latitude*toRad, o.Latitude*toRad
+	dLat := lat2 - lat1
+	dLon := (o.Longitude - v.Longitude) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Sqrt(a))
+}
+
+// VenueRegistry records the home stadium of every team and the neutral-site venues where games are played.
+type VenueRegistry struct {
+	// Teams maps teams to their home stadiums.
+	Teams map[Team]Venue
+	// Venues maps the names of neutral-site venues to venues.
+	Venues map[string]Venue
+}
+
+// venuesYaml is the format of a venues YAML file.
+type venuesYaml struct {
+	Teams  map[string]Venue `yaml:"teams"`
+	Venues map[string]Venue `yaml:"venues"`
+}
+
+// MakeVenueRegistry parses a venues YAML file.
+// Teams are named as in the schedule. Neutral-site venues are named by their keys.
+func MakeVenueRegistry(fileName string) (*VenueRegistry, error) {
+	vYaml, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var vy venuesYaml
+	err = yaml.Unmarshal(vYaml, &vy)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &VenueRegistry{Teams: make(map[Team]Venue), Venues: make(map[string]Venue)}
+	for name, v := range vy.Teams {
+		if err := v.validate(); err != nil {
+			return nil, fmt.Errorf("home stadium of team %s: %v", name, err)
+		}
+		r.Teams[Team{Name4: name}] = v
+	}
+	for name, v := range vy.Venues {
+		if err := v.validate(); err != nil {
+			return nil, fmt.Errorf("venue %s: %v", name, err)
+		}
+		v.Name = name
+		r.Venues[name] = v
+	}
+	return r, nil
+}
+
+func (v Venue) validate() error {
+	if v.Latitude < -90 || v.Latitude > 90 || v.Longitude < -180 || v.Longitude > 180 {
+		return fmt.Errorf("coordinates (%f, %f) out of range", v.Latitude, v.Longitude)
+	}
+	return nil
+}
+
+// Venue looks up a venue by name, first among the neutral-site venues, then among the home stadiums of the teams.
+func (r VenueRegistry) Venue(name string) (Venue, bool) {
+	if v, ok := r.Venues[name]; ok {
+		return v, true
+	}
+	for _, v := range r.Teams {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Venue{}, false
+}
+
+// Travel calculates the distances the two teams travel from their home stadiums to a venue.
+func (r VenueRegistry) Travel(team1, team2 Team, venue Venue) (float64, float64, error) {
+	home1, ok := r.Teams[team1]
+	if !ok {
+		return 0, 0, fmt.Errorf("team %s has no home stadium", team1.Name())
+	}
+	home2, ok := r.Teams[team2]
+	if !ok {
+		return 0, 0, fmt.Errorf("team %s has no home stadium", team2.Name())
+	}
+	return home1.Distance(venue), home2.Distance(venue), nil
+}
+
+// InferLocation determines where a game at a venue is played relative to team1.
+// A venue within HomeRadiusMiles of exactly one team's home stadium is that team's home field.
+// Otherwise the venue is Near the team that travels less, Far from the team that travels more,
+// or Neutral if the relative travel difference is less than NeutralTravelMargin.
+func (r VenueRegistry) InferLocation(team1, team2 Team, venue Venue) (RelativeLocation, error) {
+	d1, d2, err := r.Travel(team1, team2, venue)
+	if err != nil {
+		return Neutral, err
+	}
+	return locationFromTravel(d1, d2), nil
+}
+
+func locationFromTravel(d1, d2 float64) RelativeLocation {
+	home1 := d1 <= HomeRadiusMiles
+	home2 := d2 <= HomeRadiusMiles
+	switch {
+	case home1 && !home2:
+		return Home
+	case home2 && !home1:
+		return Away
+	}
+	rt := relativeTravel(d1, d2)
+	switch {
+	case math.Abs(rt) < NeutralTravelMargin:
+		return Neutral
+	case rt > 0:
+		return Near
+	default:
+		return Far
+	}
+}
+
+// relativeTravel is the difference between the distances traveled by the opponent and by the team, relative to the total distance traveled.
+// It ranges from 1 when the team travels nowhere (and the opponent does) to -1 when the opponent travels nowhere.
+func relativeTravel(d1, d2 float64) float64 {
+	if d1+d2 == 0 {
+		return 0
+	}
+	return (d2 - d1) / (d1 + d2)
+}
+
+// Game makes a game between two teams played at a venue, inferring the location and recording the venue and the distance each team travels.
+func (r VenueRegistry) Game(team1, team2 Team, venue Venue) (*Game, error) {
+	d1, d2, err := r.Travel(team1, team2, venue)
+	if err != nil {
+		return nil, err
+	}
+	g := NewGameWithTravel(team1, team2, locationFromTravel(d1, d2), d1, d2)
+	g.SetVenue(venue.Name)
+	return g, nil
+}
@@ -0,0 +1,93 @@
+package bts
+
+import (
+	"math"
+	"testing"
+)
+
+var testVenues = VenueRegistry{
+	Teams: map[Team]Venue{
+		{"ILL"}: {Name: "Memorial Stadium", Latitude: 40.0992, Longitude: -88.2360},
+		{"NW"}:  {Name: "Ryan Field", Latitude: 42.0654, Longitude: -87.6925},
+		{"PUR"}: {Name: "Ross-Ade Stadium", Latitude: 40.4352, Longitude: -86.9186},
+	},
+	Venues: map[string]Venue{
+		"Soldier Field":     {Name: "Soldier Field", Latitude: 41.8623, Longitude: -87.6167},
+		"Lucas Oil Stadium": {Name: "Lucas Oil Stadium", Latitude: 39.7601, Longitude: -86.1639},
+	},
+}
+
+func TestDistance(t *testing.T) {
+	d := testVenues.Teams[Team{"ILL"}].Distance(testVenues.Teams[Team{"NW"}])
+	// Champaign to Evanston is about 140 miles as the crow flies.
+	if d < 135 || d > 145 {
+		t.Errorf("expected about 140 miles, got %f", d)
+	}
+}
+
+func TestInferLocation(t *testing.T) {
+	ill, nw, pur := Team{"ILL"}, Team{"NW"}, Team{"PUR"}
+	tests := []struct {
+		team1, team2 Team
+		venue        string
+		expected     RelativeLocation
+	}{
+		{ill, nw, "Memorial Stadium", Home},
+		{ill, nw, "Ryan Field", Away},
+		{nw, ill, "Soldier Field", Near},
+		{ill, nw, "Soldier Field", Far},
+		{ill, pur, "Soldier Field", Neutral},
+	}
+	for _, test := range tests {
+		venue, ok := testVenues.Venue(test.venue)
+		if !ok {
+			t.Fatalf("venue %s not found", test.venue)
+		}
+		loc, err := testVenues.InferLocation(test.team1, test.team2, venue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loc != test.expected {
+			t.Errorf("%s vs %s at %s: expected %d, got %d", test.team1.Name(), test.team2.Name(), test.venue, test.expected, loc)
+		}
+	}
+
+	if _, err := testVenues.InferLocation(ill, Team{"ZZZ"}, testVenues.Venues["Soldier Field"]); err == nil {
+		t.Errorf("expected error for team without a home stadium")
+	}
+}
+
+func TestTravelSpreadModel(t *testing.T) {
+	ratings := map[Team]float64{{"ILL"}: 10, {"NW"}: 10}
+	model := NewTravelSpreadModel(ratings, 10, 3)
+	discrete := NewGaussianSpreadModel(ratings, 10, 3, 1.5)
+
+	home, err := testVenues.Game(Team{"ILL"}, Team{"NW"}, testVenues.Teams[Team{"ILL"}])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, spread := model.Predict(home); math.Abs(spread-3) > 1e-9 {
+		t.Errorf("expected full home bias of 3 at home, got %f", spread)
+	}
+
+	near, err := testVenues.Game(Team{"NW"}, Team{"ILL"}, testVenues.Venues["Soldier Field"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, _ := near.RelativeTravel(0)
+	if _, spread := model.Predict(near); math.Abs(spread-3*rt) > 1e-9 || spread <= 0 || spread >= 3 {
+		t.Errorf("expected partial home bias %f, got %f", 3*rt, spread)
+	}
+	reversed := NewGameWithTravel(Team{"ILL"}, Team{"NW"}, -near.LocationRelativeToTeam(0), near.travel[1], near.travel[0])
+	if _, spread := model.Predict(reversed); math.Abs(spread+3*rt) > 1e-9 {
+		t.Errorf("expected bias against the team traveling farther of %f, got %f", -3*rt, spread)
+	}
+
+	// Without travel distances, the travel model matches the discrete model.
+	far := NewGame(Team{"ILL"}, Team{"NW"}, Far)
+	_, s1 := model.Predict(far)
+	_, s2 := discrete.Predict(far)
+	if s1 != s2 {
+		t.Errorf("expected fallback spread %f, got %f", s2, s1)
+	}
+}